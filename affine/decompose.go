@@ -0,0 +1,51 @@
+package affine
+
+import "math"
+
+// Decomposition breaks a transformation down into the steps which make it up. Applying the
+// steps in order (scale, skew, rotate, then translate) recreates the transformation.
+type Decomposition struct {
+	TranslateX, TranslateY float64
+	// Rotation in degrees, as used by NewRotationTransformation.
+	Rotation float64
+	// ScaleX and ScaleY are the scaling factors. A negative ScaleY means that the
+	// transformation includes a reflection.
+	ScaleX, ScaleY float64
+	// Skew along the X axis, in degrees.
+	Skew float64
+}
+
+// Decompose breaks the transformation down into translation, rotation, scale and skew.
+func (t Transformation) Decompose() Decomposition {
+	d := Decomposition{
+		TranslateX: t.c,
+		TranslateY: t.r,
+	}
+
+	// The matrix is made up of R(rotation) * K(skew) * S(scale), so the first column
+	// is the X axis, scaled and rotated.
+	d.ScaleX = math.Hypot(t.a, t.p)
+	if d.ScaleX == 0 {
+		// Everything has been squashed onto the Y axis.
+		d.ScaleY = math.Hypot(t.b, t.q)
+		return d
+	}
+	d.Rotation = math.Atan2(t.p, t.a) / degreeToRad
+
+	det := (t.a * t.q) - (t.b * t.p)
+	d.ScaleY = det / d.ScaleX
+	if det != 0 {
+		d.Skew = math.Atan(((t.a*t.b)+(t.p*t.q))/det) / degreeToRad
+	}
+	return d
+}
+
+// Transformation recreates the transformation from its parts.
+func (d Decomposition) Transformation() Transformation {
+	sin, cos := math.Sincos(d.Rotation * degreeToRad)
+	tan := math.Tan(d.Skew * degreeToRad)
+	return NewTransformation([]float64{
+		d.ScaleX * cos, d.ScaleY * ((cos * tan) - sin), d.TranslateX,
+		d.ScaleX * sin, d.ScaleY * ((sin * tan) + cos), d.TranslateY,
+	})
+}
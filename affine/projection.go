@@ -0,0 +1,232 @@
+package affine
+
+import (
+	"errors"
+	"image"
+	"math"
+
+	"github.com/a-h/round"
+)
+
+// Projection represents a 3x3 matrix used to carry out a projective (perspective) transform.
+// Unlike a Transformation, the last row of the matrix is used, so that parallel lines can
+// meet at a vanishing point, e.g. to draw a floor which stretches into the distance.
+type Projection struct {
+	a, b, c float64
+	p, q, r float64
+	u, v, w float64
+}
+
+// NewProjection creates a custom projection, based on receiving an array of 9 floating points.
+func NewProjection(matrix []float64) Projection {
+	return Projection{
+		a: matrix[0],
+		b: matrix[1],
+		c: matrix[2],
+		p: matrix[3],
+		q: matrix[4],
+		r: matrix[5],
+		u: matrix[6],
+		v: matrix[7],
+		w: matrix[8],
+	}
+}
+
+// Projection converts the transformation to a projection.
+func (t Transformation) Projection() Projection {
+	return NewProjection(t.Matrix())
+}
+
+// ErrDegeneratePoints is returned when a projection can't be created from points because
+// three or more of them are on the same line.
+var ErrDegeneratePoints = errors.New("affine: three or more of the points are on the same line")
+
+// NewProjectionFromPoints creates the projection which maps each of the four source points to
+// the destination point at the same index, e.g. to map the corners of a rectangle onto a
+// trapezium.
+func NewProjectionFromPoints(src, dst [4]image.Point) (Projection, error) {
+	// Each pair of points provides two equations, giving 8 equations for the 8 unknown
+	// values of the matrix (the last value is fixed at 1).
+	// See https://en.wikipedia.org/wiki/Homography#Mathematical_definition
+	var m [8][9]float64
+	for i := 0; i < 4; i++ {
+		x, y := float64(src[i].X), float64(src[i].Y)
+		X, Y := float64(dst[i].X), float64(dst[i].Y)
+		m[i*2] = [9]float64{x, y, 1, 0, 0, 0, -x * X, -y * X, X}
+		m[i*2+1] = [9]float64{0, 0, 0, x, y, 1, -x * Y, -y * Y, Y}
+	}
+	h, ok := solve(m)
+	if !ok {
+		return Projection{}, ErrDegeneratePoints
+	}
+	pr := NewProjection([]float64{
+		h[0], h[1], h[2],
+		h[3], h[4], h[5],
+		h[6], h[7], 1,
+	})
+	// Fixing the last value at 1 leaves the sign of the matrix arbitrary, but Project
+	// treats points with a negative w as being behind the viewer. The source points are in
+	// front of the viewer, so flip the sign if their center isn't.
+	var cx, cy float64
+	for _, pt := range src {
+		cx += float64(pt.X) / 4
+		cy += float64(pt.Y) / 4
+	}
+	if pr.u*cx+pr.v*cy+pr.w < 0 {
+		pr = pr.negate()
+	}
+	return pr, nil
+}
+
+// negate returns the projection with the sign of every value flipped, which maps points to
+// the same place.
+func (pr Projection) negate() Projection {
+	return Projection{
+		-pr.a, -pr.b, -pr.c,
+		-pr.p, -pr.q, -pr.r,
+		-pr.u, -pr.v, -pr.w,
+	}
+}
+
+// solve solves the system of linear equations using Gaussian elimination, returning false
+// if there isn't a single solution.
+func solve(m [8][9]float64) (x [8]float64, ok bool) {
+	const n = 8
+	for col := 0; col < n; col++ {
+		// Use the row with the largest value as the pivot, to reduce rounding errors.
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(m[row][col]) > math.Abs(m[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-12 {
+			return x, false
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		for row := col + 1; row < n; row++ {
+			f := m[row][col] / m[col][col]
+			for k := col; k <= n; k++ {
+				m[row][k] -= f * m[col][k]
+			}
+		}
+	}
+	for row := n - 1; row >= 0; row-- {
+		sum := m[row][n]
+		for k := row + 1; k < n; k++ {
+			sum -= m[row][k] * x[k]
+		}
+		x[row] = sum / m[row][row]
+	}
+	return x, true
+}
+
+// NewYRotationProjection rotates the image around the vertical line which passes through
+// the center, as if it were a card being flipped over. The distance is how far away the
+// viewer is from the image, smaller distances give a stronger perspective effect.
+func NewYRotationProjection(degrees float64, center image.Point, distance float64) Projection {
+	sin, cos := math.Sincos(degrees * degreeToRad)
+	rotate := NewProjection([]float64{
+		cos, 0, 0,
+		0, 1, 0,
+		sin / distance, 0, 1,
+	})
+	return projectAbout(rotate, center)
+}
+
+// NewXRotationProjection tilts the image around the horizontal line which passes through
+// the center, e.g. to lay an image down as a floor. The distance is how far away the viewer
+// is from the image, smaller distances give a stronger perspective effect.
+func NewXRotationProjection(degrees float64, center image.Point, distance float64) Projection {
+	sin, cos := math.Sincos(degrees * degreeToRad)
+	rotate := NewProjection([]float64{
+		1, 0, 0,
+		0, cos, 0,
+		0, sin / distance, 1,
+	})
+	return projectAbout(rotate, center)
+}
+
+func projectAbout(p Projection, pivot image.Point) Projection {
+	moveToPivot := NewTranslationTransformation(pivot.X, pivot.Y).Projection()
+	moveBack := NewTranslationTransformation(-pivot.X, -pivot.Y).Projection()
+	return moveToPivot.Combine(p).Combine(moveBack)
+}
+
+// Project applies the projection to the coordinates. It returns false if the point can't
+// be projected, because it's at, or behind, the viewer.
+func (pr Projection) Project(x, y float64) (x1, y1 float64, ok bool) {
+	w := (pr.u * x) + (pr.v * y) + pr.w
+	if w <= 0 {
+		return 0, 0, false
+	}
+	x1 = ((pr.a * x) + (pr.b * y) + pr.c) / w
+	y1 = ((pr.p * x) + (pr.q * y) + pr.r) / w
+	return x1, y1, true
+}
+
+// Apply applies the projection to a point. Points which can't be projected are returned
+// unchanged, use Project to detect them.
+func (pr Projection) Apply(point image.Point) image.Point {
+	x1, y1, ok := pr.Project(float64(point.X), float64(point.Y))
+	if !ok {
+		return point
+	}
+	return image.Point{int(round.ToEven(x1, 0)), int(round.ToEven(y1, 0))}
+}
+
+// Combine combines two projections into a single operation.
+func (pr Projection) Combine(pr2 Projection) Projection {
+	return NewProjection([]float64{
+		(pr.a * pr2.a) + (pr.b * pr2.p) + (pr.c * pr2.u),
+		(pr.a * pr2.b) + (pr.b * pr2.q) + (pr.c * pr2.v),
+		(pr.a * pr2.c) + (pr.b * pr2.r) + (pr.c * pr2.w),
+		(pr.p * pr2.a) + (pr.q * pr2.p) + (pr.r * pr2.u),
+		(pr.p * pr2.b) + (pr.q * pr2.q) + (pr.r * pr2.v),
+		(pr.p * pr2.c) + (pr.q * pr2.r) + (pr.r * pr2.w),
+		(pr.u * pr2.a) + (pr.v * pr2.p) + (pr.w * pr2.u),
+		(pr.u * pr2.b) + (pr.v * pr2.q) + (pr.w * pr2.v),
+		(pr.u * pr2.c) + (pr.v * pr2.r) + (pr.w * pr2.w),
+	})
+}
+
+// Matrix returns the 9 elements of the projection's matrix, in the same order as used by
+// NewProjection.
+func (pr Projection) Matrix() []float64 {
+	return []float64{
+		pr.a, pr.b, pr.c,
+		pr.p, pr.q, pr.r,
+		pr.u, pr.v, pr.w,
+	}
+}
+
+// Determinant returns the determinant of the matrix.
+func (pr Projection) Determinant() float64 {
+	return pr.a*(pr.q*pr.w-pr.r*pr.v) -
+		pr.b*(pr.p*pr.w-pr.r*pr.u) +
+		pr.c*(pr.p*pr.v-pr.q*pr.u)
+}
+
+// Inverse returns the projection which undoes this projection.
+func (pr Projection) Inverse() (Projection, error) {
+	det := pr.Determinant()
+	if det == 0 || math.IsNaN(det) || math.IsInf(det, 0) {
+		return Projection{}, ErrSingularMatrix
+	}
+	return NewProjection([]float64{
+		(pr.q*pr.w - pr.r*pr.v) / det,
+		(pr.c*pr.v - pr.b*pr.w) / det,
+		(pr.b*pr.r - pr.c*pr.q) / det,
+		(pr.r*pr.u - pr.p*pr.w) / det,
+		(pr.a*pr.w - pr.c*pr.u) / det,
+		(pr.c*pr.p - pr.a*pr.r) / det,
+		(pr.p*pr.v - pr.q*pr.u) / det,
+		(pr.b*pr.u - pr.a*pr.v) / det,
+		(pr.a*pr.q - pr.b*pr.p) / det,
+	}), nil
+}
+
+// Eq compares two projections against each other.
+func (pr Projection) Eq(pr2 Projection) bool {
+	return pr == pr2
+}
@@ -0,0 +1,127 @@
+package affine
+
+import (
+	"image"
+	"testing"
+)
+
+func TestThatTransformationsCanBeUsedAsProjections(t *testing.T) {
+	transformation := NewRotationAboutTransformation(30, image.Point{10, 10}).Combine(NewScaleTransformation(2, 0.5))
+	projection := transformation.Projection()
+
+	for _, p := range []image.Point{{0, 0}, {10, 0}, {7, 13}, {-20, 40}} {
+		if expected, actual := transformation.Apply(p), projection.Apply(p); !actual.Eq(expected) {
+			t.Errorf("for input %v, expected %v, got %v", p, expected, actual)
+		}
+	}
+}
+
+func TestProjectionFromPoints(t *testing.T) {
+	square := [4]image.Point{{0, 0}, {100, 0}, {100, 100}, {0, 100}}
+
+	tests := []struct {
+		name string
+		src  [4]image.Point
+		dst  [4]image.Point
+	}{
+		{
+			name: "identity",
+			dst:  square,
+		},
+		{
+			name: "translation",
+			dst:  [4]image.Point{{10, 20}, {110, 20}, {110, 120}, {10, 120}},
+		},
+		{
+			name: "floor",
+			dst:  [4]image.Point{{40, 0}, {60, 0}, {100, 100}, {0, 100}},
+		},
+		{
+			name: "irregular",
+			dst:  [4]image.Point{{5, 3}, {90, 12}, {80, 70}, {-10, 95}},
+		},
+		{
+			// The solved matrix puts these points behind the viewer, unless its sign is
+			// flipped.
+			name: "offset source",
+			src:  [4]image.Point{{500, 500}, {600, 500}, {600, 600}, {500, 600}},
+			dst:  [4]image.Point{{0, 0}, {100, 0}, {80, 60}, {20, 60}},
+		},
+	}
+
+	for _, test := range tests {
+		src := test.src
+		if src == ([4]image.Point{}) {
+			src = square
+		}
+		projection, err := NewProjectionFromPoints(src, test.dst)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		for i := range src {
+			if _, _, ok := projection.Project(float64(src[i].X), float64(src[i].Y)); !ok {
+				t.Errorf("%s: expected %v to be in front of the viewer", test.name, src[i])
+			}
+			if actual := projection.Apply(src[i]); !actual.Eq(test.dst[i]) {
+				t.Errorf("%s: expected %v to map to %v, got %v", test.name, src[i], test.dst[i], actual)
+			}
+		}
+		inverse, err := projection.Inverse()
+		if err != nil {
+			t.Errorf("%s: unexpected error inverting: %v", test.name, err)
+			continue
+		}
+		for i := range src {
+			if actual := inverse.Apply(test.dst[i]); !actual.Eq(src[i]) {
+				t.Errorf("%s: expected the inverse to map %v to %v, got %v", test.name, test.dst[i], src[i], actual)
+			}
+		}
+	}
+}
+
+func TestProjectionFromDegeneratePoints(t *testing.T) {
+	line := [4]image.Point{{0, 0}, {1, 1}, {2, 2}, {3, 3}}
+	square := [4]image.Point{{0, 0}, {100, 0}, {100, 100}, {0, 100}}
+	if _, err := NewProjectionFromPoints(line, square); err != ErrDegeneratePoints {
+		t.Errorf("expected ErrDegeneratePoints, got %v", err)
+	}
+}
+
+func TestYRotationProjection(t *testing.T) {
+	center := image.Point{50, 50}
+
+	// No rotation has no effect.
+	flat := NewYRotationProjection(0, center, 500)
+	if actual := flat.Apply(image.Point{0, 0}); !actual.Eq(image.Point{0, 0}) {
+		t.Errorf("expected no change without rotation, got %v", actual)
+	}
+
+	// Rotating makes one side of the card nearer to the viewer, and so taller.
+	flip := NewYRotationProjection(45, center, 500)
+	if actual := flip.Apply(center); !actual.Eq(center) {
+		t.Errorf("expected the center to remain at %v, got %v", center, actual)
+	}
+	left := flip.Apply(image.Point{0, 0})
+	right := flip.Apply(image.Point{100, 0})
+	if left.Y >= 0 || right.Y <= 0 {
+		t.Errorf("expected the left edge to get taller and the right edge to get shorter, got %v and %v", left, right)
+	}
+	if left.X <= 0 || right.X >= 100 {
+		t.Errorf("expected the card to get narrower, got %v and %v", left, right)
+	}
+}
+
+func TestProjectingPointsBehindTheViewer(t *testing.T) {
+	p := NewProjection([]float64{
+		1, 0, 0,
+		0, 1, 0,
+		-0.1, 0, 1,
+	})
+	if _, _, ok := p.Project(20, 0); ok {
+		t.Error("expected the point to be behind the viewer")
+	}
+	if _, _, ok := p.Project(5, 0); !ok {
+		t.Error("expected the point to be in front of the viewer")
+	}
+}
@@ -1,6 +1,7 @@
 package affine
 
 import (
+	"errors"
 	"image"
 	"math"
 
@@ -43,12 +44,8 @@ var IdentityMatrix = []float64{
 }
 
 // NewScaleTransformation applies a scaling factor to the width and height, e.g.
-// a width of 0.5 would be half the size. Numbers greater than one will be ignored
-// as inputs.
+// a width of 0.5 would be half the size, and a width of 2 would be double the size.
 func NewScaleTransformation(width, height float64) Transformation {
-	if width > 1 || height > 1 {
-		return NewTransformation(IdentityMatrix)
-	}
 	return NewTransformation([]float64{
 		width, 0, 0,
 		0, height, 0,
@@ -63,6 +60,27 @@ func NewTranslationTransformation(x, y int) Transformation {
 	})
 }
 
+// NewScaleAboutTransformation scales around the pivot point, so that the pivot stays
+// in the same place, e.g. to grow a shape from its center.
+func NewScaleAboutTransformation(width, height float64, pivot image.Point) Transformation {
+	return aboutPivot(NewScaleTransformation(width, height), pivot)
+}
+
+// NewShearTransformation slants the image. Each point moves horizontally by x times its Y
+// coordinate, and vertically by y times its X coordinate.
+func NewShearTransformation(x, y float64) Transformation {
+	return NewTransformation([]float64{
+		1, x, 0,
+		y, 1, 0,
+	})
+}
+
+// NewSkewTransformation slants the image by the angles (in degrees) from the Y axis and
+// X axis respectively.
+func NewSkewTransformation(xDegrees, yDegrees float64) Transformation {
+	return NewShearTransformation(math.Tan(xDegrees*degreeToRad), math.Tan(yDegrees*degreeToRad))
+}
+
 // NewReflectionTransformation mirrors the image.
 func NewReflectionTransformation() Transformation {
 	return NewTransformation([]float64{
@@ -71,6 +89,18 @@ func NewReflectionTransformation() Transformation {
 	})
 }
 
+// NewLineReflectionTransformation mirrors the image across the line which passes through
+// the point at the angle (in degrees) given, e.g. an angle of 0 mirrors the image top to
+// bottom, and an angle of 90 mirrors the image left to right.
+func NewLineReflectionTransformation(through image.Point, degrees float64) Transformation {
+	sin, cos := math.Sincos(2 * degrees * degreeToRad)
+	reflect := NewTransformation([]float64{
+		cos, sin, 0,
+		sin, -cos, 0,
+	})
+	return aboutPivot(reflect, through)
+}
+
 const degreeToRad = math.Pi / float64(180)
 
 // NewRotationTransformation creates a transformation which rotates by the specified amount.
@@ -86,6 +116,19 @@ func NewRotationTransformation(degrees float64) Transformation {
 	})
 }
 
+// NewRotationAboutTransformation rotates around the pivot point instead of the origin,
+// e.g. to spin a shape around its center.
+func NewRotationAboutTransformation(degrees float64, pivot image.Point) Transformation {
+	return aboutPivot(NewRotationTransformation(degrees), pivot)
+}
+
+// aboutPivot moves the pivot to the origin, applies the transformation, and moves it back.
+func aboutPivot(t Transformation, pivot image.Point) Transformation {
+	moveToPivot := NewTranslationTransformation(pivot.X, pivot.Y)
+	moveBack := NewTranslationTransformation(-pivot.X, -pivot.Y)
+	return moveToPivot.Combine(t).Combine(moveBack)
+}
+
 // Apply applies the transformation to a point.
 func (t Transformation) Apply(point image.Point) image.Point {
 	// See https://en.wikipedia.org/wiki/Matrix_multiplication#Matrix_product_.28two_matrices.29
@@ -127,3 +170,49 @@ func (t Transformation) Eq(t2 Transformation) bool {
 		t.v == t2.v &&
 		t.w == t2.w
 }
+
+// Matrix returns the 9 elements of the transformation's matrix, in the same order as
+// used by NewTransformation.
+func (t Transformation) Matrix() []float64 {
+	return []float64{
+		t.a, t.b, t.c,
+		t.p, t.q, t.r,
+		t.u, t.v, t.w,
+	}
+}
+
+// Translation returns the distance that the transformation moves points by.
+func (t Transformation) Translation() (x, y float64) {
+	return t.c, t.r
+}
+
+// Determinant returns the determinant of the matrix. A determinant of zero means that the
+// transformation squashes everything into a line or point, and can't be inverted. A negative
+// determinant means that the transformation mirrors points.
+func (t Transformation) Determinant() float64 {
+	return t.a*(t.q*t.w-t.r*t.v) -
+		t.b*(t.p*t.w-t.r*t.u) +
+		t.c*(t.p*t.v-t.q*t.u)
+}
+
+// ErrSingularMatrix is returned when attempting to invert a transformation which can't be inverted.
+var ErrSingularMatrix = errors.New("affine: the transformation matrix is singular and cannot be inverted")
+
+// Inverse returns the transformation which undoes this transformation, e.g. the inverse of
+// a rotation by 45 degrees is a rotation by -45 degrees. Combining a transformation with its
+// inverse results in the identity matrix.
+func (t Transformation) Inverse() (Transformation, error) {
+	det := t.Determinant()
+	if det == 0 || math.IsNaN(det) || math.IsInf(det, 0) {
+		return Transformation{}, ErrSingularMatrix
+	}
+	// See https://en.wikipedia.org/wiki/Invertible_matrix#Inversion_of_3_%C3%97_3_matrices
+	return NewTransformation([]float64{
+		(t.q*t.w - t.r*t.v) / det,
+		(t.c*t.v - t.b*t.w) / det,
+		(t.b*t.r - t.c*t.q) / det,
+		(t.r*t.u - t.p*t.w) / det,
+		(t.a*t.w - t.c*t.u) / det,
+		(t.c*t.p - t.a*t.r) / det,
+	}), nil
+}
@@ -247,22 +247,22 @@ func TestScaleTransformation(t *testing.T) {
 			},
 		},
 		{
-			name: "Ignore high values",
+			name: "Double the size",
 			input: []image.Point{
 				image.Point{0, 0},
 				image.Point{1, 0},
-				image.Point{2, 0},
-				image.Point{3, 0},
-				image.Point{4, 0},
+				image.Point{2, 1},
+				image.Point{3, 2},
+				image.Point{4, 3},
 			},
-			scaleWidth:  1.5,
-			scaleHeight: 1.5,
+			scaleWidth:  2,
+			scaleHeight: 2,
 			expected: []image.Point{
 				image.Point{0, 0},
-				image.Point{1, 0},
 				image.Point{2, 0},
-				image.Point{3, 0},
-				image.Point{4, 0},
+				image.Point{4, 2},
+				image.Point{6, 4},
+				image.Point{8, 6},
 			},
 		},
 	}
@@ -332,3 +332,239 @@ func TestIdentityMatrixTransform(t *testing.T) {
 		}
 	}
 }
+
+func TestDeterminant(t *testing.T) {
+	tests := []struct {
+		name           string
+		transformation Transformation
+		expected       float64
+	}{
+		{
+			name:           "identity",
+			transformation: NewTransformation(IdentityMatrix),
+			expected:       1,
+		},
+		{
+			name:           "rotation",
+			transformation: NewRotationTransformation(30),
+			expected:       1,
+		},
+		{
+			name:           "scale",
+			transformation: NewScaleTransformation(0.5, 0.5),
+			expected:       0.25,
+		},
+		{
+			name:           "reflection",
+			transformation: NewReflectionTransformation(),
+			expected:       -1,
+		},
+		{
+			name:           "translation",
+			transformation: NewTranslationTransformation(10, 20),
+			expected:       1,
+		},
+	}
+
+	for _, test := range tests {
+		actual := test.transformation.Determinant()
+		if !tolerance.IsWithin(actual, test.expected, tolerance.ThreeDecimalPlaces) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestInverse(t *testing.T) {
+	transformations := []Transformation{
+		NewTransformation(IdentityMatrix),
+		NewRotationTransformation(30),
+		NewScaleTransformation(0.5, 0.25),
+		NewReflectionTransformation(),
+		NewTranslationTransformation(10, -20),
+		NewTranslationTransformation(50, 50).Combine(NewRotationTransformation(45)).Combine(NewTranslationTransformation(-50, -50)),
+	}
+
+	for _, transformation := range transformations {
+		inverse, err := transformation.Inverse()
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", transformation, err)
+			continue
+		}
+		identity := transformation.Combine(inverse)
+		if !matrixIsWithin(identity.Matrix(), IdentityMatrix) {
+			t.Errorf("%v: expected combining with the inverse to result in the identity matrix, got %v", transformation, identity.Matrix())
+		}
+		p := image.Point{8, 12}
+		if actual := inverse.Apply(transformation.Apply(p)); !actual.Eq(p) {
+			t.Errorf("%v: expected the inverse to map %v back to itself, got %v", transformation, p, actual)
+		}
+	}
+}
+
+func TestInverseOfSingularMatrix(t *testing.T) {
+	_, err := NewScaleTransformation(0, 1).Inverse()
+	if err != ErrSingularMatrix {
+		t.Errorf("expected ErrSingularMatrix, got %v", err)
+	}
+}
+
+func TestDecomposition(t *testing.T) {
+	tests := []struct {
+		name           string
+		transformation Transformation
+		expected       Decomposition
+	}{
+		{
+			name:           "identity",
+			transformation: NewTransformation(IdentityMatrix),
+			expected:       Decomposition{ScaleX: 1, ScaleY: 1},
+		},
+		{
+			name:           "translation",
+			transformation: NewTranslationTransformation(10, -5),
+			expected:       Decomposition{TranslateX: 10, TranslateY: -5, ScaleX: 1, ScaleY: 1},
+		},
+		{
+			name:           "rotation",
+			transformation: NewRotationTransformation(30),
+			expected:       Decomposition{Rotation: 30, ScaleX: 1, ScaleY: 1},
+		},
+		{
+			name:           "scale",
+			transformation: NewScaleTransformation(0.5, 0.25),
+			expected:       Decomposition{ScaleX: 0.5, ScaleY: 0.25},
+		},
+		{
+			name:           "reflection",
+			transformation: NewReflectionTransformation(),
+			expected:       Decomposition{ScaleX: 1, ScaleY: -1},
+		},
+		{
+			name: "everything",
+			transformation: Decomposition{
+				TranslateX: 3, TranslateY: 4, Rotation: -60, ScaleX: 0.5, ScaleY: 0.75, Skew: 10,
+			}.Transformation(),
+			expected: Decomposition{TranslateX: 3, TranslateY: 4, Rotation: -60, ScaleX: 0.5, ScaleY: 0.75, Skew: 10},
+		},
+	}
+
+	for _, test := range tests {
+		actual := test.transformation.Decompose()
+		expected := []float64{test.expected.TranslateX, test.expected.TranslateY, test.expected.Rotation, test.expected.ScaleX, test.expected.ScaleY, test.expected.Skew}
+		values := []float64{actual.TranslateX, actual.TranslateY, actual.Rotation, actual.ScaleX, actual.ScaleY, actual.Skew}
+		if !matrixIsWithin(values, expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, actual)
+		}
+		if recomposed := actual.Transformation(); !matrixIsWithin(recomposed.Matrix(), test.transformation.Matrix()) {
+			t.Errorf("%s: expected recomposing to result in %v, got %v", test.name, test.transformation.Matrix(), recomposed.Matrix())
+		}
+	}
+}
+
+func matrixIsWithin(actual, expected []float64) bool {
+	for i := range expected {
+		if !tolerance.IsWithin(actual[i], expected[i], tolerance.ThreeDecimalPlaces) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestShearTransformation(t *testing.T) {
+	tests := []struct {
+		name           string
+		transformation Transformation
+		input          image.Point
+		expected       image.Point
+	}{
+		{
+			name:           "shear x",
+			transformation: NewShearTransformation(0.5, 0),
+			input:          image.Point{0, 10},
+			expected:       image.Point{5, 10},
+		},
+		{
+			name:           "shear y",
+			transformation: NewShearTransformation(0, 2),
+			input:          image.Point{3, 0},
+			expected:       image.Point{3, 6},
+		},
+		{
+			name:           "skew 45 degrees",
+			transformation: NewSkewTransformation(45, 0),
+			input:          image.Point{0, 10},
+			expected:       image.Point{10, 10},
+		},
+	}
+
+	for _, test := range tests {
+		actual := test.transformation.Apply(test.input)
+		if !actual.Eq(test.expected) {
+			t.Errorf("%s: for input %v, expected %v, got %v", test.name, test.input, test.expected, actual)
+		}
+	}
+}
+
+func TestLineReflectionTransformation(t *testing.T) {
+	tests := []struct {
+		name     string
+		through  image.Point
+		degrees  float64
+		input    image.Point
+		expected image.Point
+	}{
+		{
+			name:     "horizontal line through origin",
+			degrees:  0,
+			input:    image.Point{3, 4},
+			expected: image.Point{3, -4},
+		},
+		{
+			name:     "vertical line through 10, 0",
+			through:  image.Point{10, 0},
+			degrees:  90,
+			input:    image.Point{7, 4},
+			expected: image.Point{13, 4},
+		},
+		{
+			name:     "diagonal line",
+			degrees:  45,
+			input:    image.Point{3, 8},
+			expected: image.Point{8, 3},
+		},
+		{
+			name:     "point on the line",
+			through:  image.Point{5, 5},
+			degrees:  30,
+			input:    image.Point{5, 5},
+			expected: image.Point{5, 5},
+		},
+	}
+
+	for _, test := range tests {
+		actual := NewLineReflectionTransformation(test.through, test.degrees).Apply(test.input)
+		if !actual.Eq(test.expected) {
+			t.Errorf("%s: for input %v, expected %v, got %v", test.name, test.input, test.expected, actual)
+		}
+	}
+}
+
+func TestPivotTransformations(t *testing.T) {
+	pivot := image.Point{50, 50}
+
+	rotate := NewRotationAboutTransformation(90, pivot)
+	if actual := rotate.Apply(pivot); !actual.Eq(pivot) {
+		t.Errorf("rotation: expected the pivot to stay at %v, got %v", pivot, actual)
+	}
+	if actual, expected := rotate.Apply(image.Point{60, 50}), (image.Point{50, 60}); !actual.Eq(expected) {
+		t.Errorf("rotation: expected %v, got %v", expected, actual)
+	}
+
+	scale := NewScaleAboutTransformation(2, 3, pivot)
+	if actual := scale.Apply(pivot); !actual.Eq(pivot) {
+		t.Errorf("scale: expected the pivot to stay at %v, got %v", pivot, actual)
+	}
+	if actual, expected := scale.Apply(image.Point{60, 40}), (image.Point{70, 20}); !actual.Eq(expected) {
+		t.Errorf("scale: expected %v, got %v", expected, actual)
+	}
+}
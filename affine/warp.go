@@ -0,0 +1,237 @@
+package affine
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/a-h/raster/internal/composite"
+)
+
+// Interpolation defines how the colors of a source image are sampled when warping it.
+type Interpolation int
+
+const (
+	// NearestNeighbor uses the color of the closest pixel. It's fast, and keeps hard edges,
+	// but looks blocky when scaled up.
+	NearestNeighbor Interpolation = iota
+	// Bilinear blends the 4 closest pixels.
+	Bilinear
+	// Bicubic blends the 16 closest pixels, which gives a sharper result than Bilinear.
+	Bicubic
+)
+
+// EdgeMode defines the color of points which are outside of the source image.
+type EdgeMode int
+
+const (
+	// EdgeTransparent leaves points outside of the source image undrawn.
+	EdgeTransparent EdgeMode = iota
+	// EdgeClamp repeats the pixels at the edge of the source image.
+	EdgeClamp
+	// EdgeWrap tiles the source image.
+	EdgeWrap
+	// EdgeMirror tiles the source image, mirroring every other tile.
+	EdgeMirror
+)
+
+// ApplyFloat applies the transformation to the coordinates, without rounding.
+func (t Transformation) ApplyFloat(x, y float64) (x1, y1 float64) {
+	x1 = (t.a * x) + (t.b * y) + t.c
+	y1 = (t.p * x) + (t.q * y) + t.r
+	return x1, y1
+}
+
+// Warp draws the src image onto the dst image through the transformation, e.g. to rotate
+// or scale a sprite. Each destination pixel is mapped back to the source image using the
+// inverse of the transformation, so the result has no gaps, regardless of the scale. Like
+// draw.Draw, the op determines whether the pixels replace (draw.Src) or are drawn over
+// (draw.Over) the pixels of dst. An error is returned if the transformation can't be inverted.
+func Warp(dst draw.Image, src image.Image, t Transformation, interpolation Interpolation, edge EdgeMode, op draw.Op) error {
+	inverse, err := t.Inverse()
+	if err != nil {
+		return err
+	}
+
+	area := dst.Bounds()
+	if edge == EdgeTransparent {
+		// Allow an extra pixel for interpolation to blend into.
+		area = area.Intersect(TransformedBounds(t, src.Bounds()).Inset(-1))
+	}
+
+	s := sampler{src: src, bounds: src.Bounds(), edge: edge}
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			// Sample from the center of the pixel.
+			sx, sy := inverse.ApplyFloat(float64(x)+0.5, float64(y)+0.5)
+			c, ok := s.sample(sx-0.5, sy-0.5, interpolation)
+			if !ok {
+				continue
+			}
+			if op == draw.Over {
+				c = composite.Over(dst.At(x, y), c)
+			}
+			dst.Set(x, y, c)
+		}
+	}
+	return nil
+}
+
+// TransformedBounds returns the smallest rectangle which contains the corners of r after
+// they've been transformed, e.g. the area covered by a rotated image.
+func TransformedBounds(t Transformation, r image.Rectangle) image.Rectangle {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	corners := [][2]float64{
+		{float64(r.Min.X), float64(r.Min.Y)},
+		{float64(r.Max.X), float64(r.Min.Y)},
+		{float64(r.Max.X), float64(r.Max.Y)},
+		{float64(r.Min.X), float64(r.Max.Y)},
+	}
+	for _, corner := range corners {
+		x, y := t.ApplyFloat(corner[0], corner[1])
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+	}
+	return image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY)))
+}
+
+type sampler struct {
+	src    image.Image
+	bounds image.Rectangle
+	edge   EdgeMode
+}
+
+// sample returns the color at the (pixel center) coordinates in the source image. It returns
+// false if the coordinates are outside of the image and the edge mode is EdgeTransparent.
+func (s sampler) sample(x, y float64, interpolation Interpolation) (color.Color, bool) {
+	if s.edge == EdgeTransparent {
+		// Allow half a pixel outside the image, so the edge pixels are fully drawn.
+		b := s.bounds
+		if x < float64(b.Min.X)-0.5 || y < float64(b.Min.Y)-0.5 || x >= float64(b.Max.X)-0.5 || y >= float64(b.Max.Y)-0.5 {
+			return nil, false
+		}
+	}
+
+	switch interpolation {
+	case Bilinear:
+		return s.bilinear(x, y), true
+	case Bicubic:
+		return s.bicubic(x, y), true
+	}
+	return s.at(int(math.Floor(x+0.5)), int(math.Floor(y+0.5))), true
+}
+
+func (s sampler) bilinear(x, y float64) color.Color {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+	ix, iy := int(x0), int(y0)
+
+	var sum [4]float64
+	weights := [4]float64{(1 - fx) * (1 - fy), fx * (1 - fy), (1 - fx) * fy, fx * fy}
+	points := [4]image.Point{{ix, iy}, {ix + 1, iy}, {ix, iy + 1}, {ix + 1, iy + 1}}
+	for i, p := range points {
+		r, g, b, a := s.at(p.X, p.Y).RGBA()
+		sum[0] += float64(r) * weights[i]
+		sum[1] += float64(g) * weights[i]
+		sum[2] += float64(b) * weights[i]
+		sum[3] += float64(a) * weights[i]
+	}
+	return toRGBA64(sum)
+}
+
+func (s sampler) bicubic(x, y float64) color.Color {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+	ix, iy := int(x0), int(y0)
+
+	var sum [4]float64
+	for j := -1; j <= 2; j++ {
+		wy := cubic(float64(j) - fy)
+		for i := -1; i <= 2; i++ {
+			w := cubic(float64(i)-fx) * wy
+			r, g, b, a := s.at(ix+i, iy+j).RGBA()
+			sum[0] += float64(r) * w
+			sum[1] += float64(g) * w
+			sum[2] += float64(b) * w
+			sum[3] += float64(a) * w
+		}
+	}
+	return toRGBA64(sum)
+}
+
+// cubic is the Catmull-Rom spline weight for a pixel at distance d.
+func cubic(d float64) float64 {
+	d = math.Abs(d)
+	switch {
+	case d < 1:
+		return 1.5*d*d*d - 2.5*d*d + 1
+	case d < 2:
+		return -0.5*d*d*d + 2.5*d*d - 4*d + 2
+	}
+	return 0
+}
+
+// toRGBA64 converts summed premultiplied values to a color, clamping them so that the
+// color values don't exceed the alpha value.
+func toRGBA64(sum [4]float64) color.RGBA64 {
+	a := math.Max(0, math.Min(0xffff, math.Round(sum[3])))
+	channel := func(v float64) uint16 {
+		return uint16(math.Max(0, math.Min(a, math.Round(v))))
+	}
+	return color.RGBA64{
+		R: channel(sum[0]),
+		G: channel(sum[1]),
+		B: channel(sum[2]),
+		A: uint16(a),
+	}
+}
+
+// at returns the color of the pixel, applying the edge mode to pixels outside of the image.
+func (s sampler) at(x, y int) color.Color {
+	b := s.bounds
+	if b.Empty() {
+		return color.Transparent
+	}
+	switch s.edge {
+	case EdgeClamp:
+		x = clampInt(x, b.Min.X, b.Max.X-1)
+		y = clampInt(y, b.Min.Y, b.Max.Y-1)
+	case EdgeWrap:
+		x = b.Min.X + mod(x-b.Min.X, b.Dx())
+		y = b.Min.Y + mod(y-b.Min.Y, b.Dy())
+	case EdgeMirror:
+		x = b.Min.X + mirror(x-b.Min.X, b.Dx())
+		y = b.Min.Y + mirror(y-b.Min.Y, b.Dy())
+	default:
+		if !(image.Point{x, y}).In(b) {
+			return color.Transparent
+		}
+	}
+	return s.src.At(x, y)
+}
+
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// mod returns a positive remainder, even when v is negative.
+func mod(v, n int) int {
+	return ((v % n) + n) % n
+}
+
+// mirror maps v into the range 0 to n-1, reflecting every other repetition.
+func mirror(v, n int) int {
+	v = mod(v, n*2)
+	if v >= n {
+		return (n*2 - 1) - v
+	}
+	return v
+}
@@ -0,0 +1,184 @@
+package affine
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+var (
+	red   = color.RGBA{0xff, 0, 0, 0xff}
+	blue  = color.RGBA{0, 0, 0xff, 0xff}
+	white = color.RGBA{0xff, 0xff, 0xff, 0xff}
+)
+
+// newSprite creates a 4x2 image, red on the left and blue on the right.
+func newSprite() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			if x < 2 {
+				img.Set(x, y, red)
+			} else {
+				img.Set(x, y, blue)
+			}
+		}
+	}
+	return img
+}
+
+func TestWarpWithIdentityCopiesTheImage(t *testing.T) {
+	interpolations := []Interpolation{NearestNeighbor, Bilinear, Bicubic}
+	for _, interpolation := range interpolations {
+		src := newSprite()
+		dst := image.NewRGBA(src.Bounds())
+		if err := Warp(dst, src, NewTransformation(IdentityMatrix), interpolation, EdgeClamp, draw.Src); err != nil {
+			t.Fatalf("%v: unexpected error: %v", interpolation, err)
+		}
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 4; x++ {
+				if dst.At(x, y) != src.At(x, y) {
+					t.Errorf("%v: {%d, %d}: expected %v, got %v", interpolation, x, y, src.At(x, y), dst.At(x, y))
+				}
+			}
+		}
+	}
+}
+
+func TestWarpScalesWithoutGaps(t *testing.T) {
+	src := newSprite()
+	dst := image.NewRGBA(image.Rect(0, 0, 16, 8))
+
+	if err := Warp(dst, src, NewScaleTransformation(4, 4), NearestNeighbor, EdgeTransparent, draw.Src); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			expected := red
+			if x >= 8 {
+				expected = blue
+			}
+			if dst.At(x, y) != expected {
+				t.Errorf("{%d, %d}: expected %v, got %v", x, y, expected, dst.At(x, y))
+			}
+		}
+	}
+}
+
+func TestWarpRotation(t *testing.T) {
+	src := newSprite()
+	dst := image.NewRGBA(image.Rect(-2, -4, 2, 4))
+
+	// Rotating by 90 degrees turns the sprite so that red is at the top.
+	if err := Warp(dst, src, NewRotationTransformation(90), NearestNeighbor, EdgeTransparent, draw.Src); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dst.At(-1, 0) != red {
+		t.Errorf("{-1, 0}: expected red, got %v", dst.At(-1, 0))
+	}
+	if dst.At(-1, 3) != blue {
+		t.Errorf("{-1, 3}: expected blue, got %v", dst.At(-1, 3))
+	}
+	if dst.At(1, 0) != (color.RGBA{}) {
+		t.Errorf("{1, 0}: expected the pixel to be left undrawn, got %v", dst.At(1, 0))
+	}
+}
+
+func TestWarpBilinearBlends(t *testing.T) {
+	src := newSprite()
+	dst := image.NewRGBA(image.Rect(0, 0, 8, 4))
+
+	if err := Warp(dst, src, NewScaleTransformation(2, 2), Bilinear, EdgeClamp, draw.Src); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Where red and blue meet, the colors should be mixed.
+	c := dst.RGBAAt(4, 1)
+	if c.R == 0 || c.B == 0 {
+		t.Errorf("expected red and blue to be blended, got %v", c)
+	}
+	if dst.At(0, 0) != red {
+		t.Errorf("{0, 0}: expected red, got %v", dst.At(0, 0))
+	}
+}
+
+func TestWarpEdgeModes(t *testing.T) {
+	src := newSprite()
+
+	tests := []struct {
+		edge     EdgeMode
+		expected color.Color
+	}{
+		{edge: EdgeTransparent, expected: white},
+		{edge: EdgeClamp, expected: blue},
+		{edge: EdgeWrap, expected: red},
+		{edge: EdgeMirror, expected: blue},
+	}
+
+	for _, test := range tests {
+		dst := image.NewRGBA(image.Rect(0, 0, 8, 2))
+		for x := 0; x < 8; x++ {
+			dst.Set(x, 0, white)
+		}
+		if err := Warp(dst, src, NewTransformation(IdentityMatrix), NearestNeighbor, test.edge, draw.Src); err != nil {
+			t.Fatalf("%v: unexpected error: %v", test.edge, err)
+		}
+		// Pixel 4 is just past the right hand edge of the source.
+		if dst.At(4, 0) != test.expected {
+			t.Errorf("%v: expected %v, got %v", test.edge, test.expected, dst.At(4, 0))
+		}
+	}
+}
+
+func TestWarpWithSingularMatrix(t *testing.T) {
+	src := newSprite()
+	dst := image.NewRGBA(src.Bounds())
+	if err := Warp(dst, src, NewScaleTransformation(0, 0), NearestNeighbor, EdgeClamp, draw.Src); err != ErrSingularMatrix {
+		t.Errorf("expected ErrSingularMatrix, got %v", err)
+	}
+}
+
+func TestWarpOver(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	// A half transparent red pixel, next to a transparent pixel.
+	src.SetRGBA(0, 0, color.RGBA{0x80, 0, 0, 0x80})
+
+	tests := []struct {
+		op            draw.Op
+		expectedLeft  color.RGBA
+		expectedRight color.RGBA
+	}{
+		{
+			op:            draw.Src,
+			expectedLeft:  color.RGBA{0x80, 0, 0, 0x80},
+			expectedRight: color.RGBA{},
+		},
+		{
+			op:            draw.Over,
+			expectedLeft:  color.RGBA{0xff, 0x7f, 0x7f, 0xff},
+			expectedRight: white,
+		},
+	}
+
+	for _, test := range tests {
+		dst := image.NewRGBA(image.Rect(0, 0, 2, 1))
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(white), image.Point{}, draw.Src)
+		if err := Warp(dst, src, NewTransformation(IdentityMatrix), NearestNeighbor, EdgeTransparent, test.op); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if actual := dst.RGBAAt(0, 0); actual != test.expectedLeft {
+			t.Errorf("op %v: {0, 0}: expected %v, got %v", test.op, test.expectedLeft, actual)
+		}
+		if actual := dst.RGBAAt(1, 0); actual != test.expectedRight {
+			t.Errorf("op %v: {1, 0}: expected %v, got %v", test.op, test.expectedRight, actual)
+		}
+	}
+}
+
+func TestTransformedBounds(t *testing.T) {
+	tr := NewTranslationTransformation(1, -1).Combine(NewScaleTransformation(2, 3))
+	expected := image.Rect(1, -1, 9, 5)
+	if actual := TransformedBounds(tr, image.Rect(0, 0, 4, 2)); actual != expected {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
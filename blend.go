@@ -0,0 +1,98 @@
+package raster
+
+import (
+	"image/color"
+	"math"
+)
+
+// BlendMode defines how the pixels of a shape are combined with the pixels which are already
+// on the image.
+type BlendMode int
+
+const (
+	// BlendNormal draws the pixels over the top of the existing pixels.
+	BlendNormal BlendMode = iota
+	// BlendMultiply multiplies the colors together, which always results in a darker color.
+	BlendMultiply
+	// BlendScreen inverts the colors, multiplies them, and inverts the result, which always
+	// results in a lighter color.
+	BlendScreen
+	// BlendAdditive adds the colors together, clamping at white.
+	BlendAdditive
+	// BlendOverlay multiplies dark areas and screens light areas of the existing pixels.
+	BlendOverlay
+)
+
+// String returns the name of the blend mode.
+func (m BlendMode) String() string {
+	switch m {
+	case BlendNormal:
+		return "normal"
+	case BlendMultiply:
+		return "multiply"
+	case BlendScreen:
+		return "screen"
+	case BlendAdditive:
+		return "additive"
+	case BlendOverlay:
+		return "overlay"
+	}
+	return "unknown"
+}
+
+// Blend combines the src color with the dst color which is already on the image. The opacity
+// (0 to 1) is applied to the src color's alpha before combining.
+func (m BlendMode) Blend(dst, src color.Color, opacity float64) color.RGBA {
+	sr, sg, sb, sa := normalise(src)
+	dr, dg, db, da := normalise(dst)
+
+	sa *= clamp(opacity)
+
+	r := composite(m.channel(dr, sr), dr, da, sr, sa)
+	g := composite(m.channel(dg, sg), dg, da, sg, sa)
+	b := composite(m.channel(db, sb), db, da, sb, sa)
+	a := sa + da*(1-sa)
+
+	return color.RGBA{
+		R: uint8(math.Round(clamp(r) * 0xff)),
+		G: uint8(math.Round(clamp(g) * 0xff)),
+		B: uint8(math.Round(clamp(b) * 0xff)),
+		A: uint8(math.Round(clamp(a) * 0xff)),
+	}
+}
+
+// channel blends a single, non-premultiplied color channel.
+func (m BlendMode) channel(dst, src float64) float64 {
+	switch m {
+	case BlendMultiply:
+		return dst * src
+	case BlendScreen:
+		return dst + src - (dst * src)
+	case BlendAdditive:
+		return math.Min(1, dst+src)
+	case BlendOverlay:
+		if dst <= 0.5 {
+			return 2 * dst * src
+		}
+		return 1 - 2*(1-dst)*(1-src)
+	}
+	return src
+}
+
+// composite returns the premultiplied result of drawing the blended channel over the
+// destination, see https://www.w3.org/TR/compositing-1/#blending
+func composite(blended, dst, dstAlpha, src, srcAlpha float64) float64 {
+	// Where there's nothing underneath, the source is used as-is.
+	mixed := (1-dstAlpha)*src + dstAlpha*blended
+	return srcAlpha*mixed + (1-srcAlpha)*dstAlpha*dst
+}
+
+// normalise converts a color to non-premultiplied channels in the range 0 to 1.
+func normalise(c color.Color) (r, g, b, a float64) {
+	nc := color.NRGBA64Model.Convert(c).(color.NRGBA64)
+	return float64(nc.R) / 0xffff, float64(nc.G) / 0xffff, float64(nc.B) / 0xffff, float64(nc.A) / 0xffff
+}
+
+func clamp(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
@@ -0,0 +1,167 @@
+package raster
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/a-h/raster/affine"
+
+	"golang.org/x/image/colornames"
+)
+
+func TestBlendModes(t *testing.T) {
+	grey := color.RGBA{0x80, 0x80, 0x80, 0xff}
+	dark := color.RGBA{0x40, 0x40, 0x40, 0xff}
+	light := color.RGBA{0xc0, 0xc0, 0xc0, 0xff}
+
+	tests := []struct {
+		mode     BlendMode
+		dst      color.Color
+		src      color.Color
+		opacity  float64
+		expected color.RGBA
+	}{
+		{
+			mode:     BlendNormal,
+			dst:      colornames.White,
+			src:      colornames.Black,
+			opacity:  1,
+			expected: color.RGBA{0, 0, 0, 0xff},
+		},
+		{
+			mode:     BlendNormal,
+			dst:      colornames.White,
+			src:      colornames.Black,
+			opacity:  0.5,
+			expected: color.RGBA{0x80, 0x80, 0x80, 0xff},
+		},
+		{
+			mode:     BlendNormal,
+			dst:      colornames.White,
+			src:      colornames.Black,
+			opacity:  0,
+			expected: color.RGBA{0xff, 0xff, 0xff, 0xff},
+		},
+		{
+			mode:     BlendNormal,
+			dst:      color.RGBA{},
+			src:      colornames.Red,
+			opacity:  0.5,
+			expected: color.RGBA{0x80, 0, 0, 0x80},
+		},
+		{
+			mode:     BlendMultiply,
+			dst:      grey,
+			src:      grey,
+			opacity:  1,
+			expected: color.RGBA{0x40, 0x40, 0x40, 0xff},
+		},
+		{
+			mode:     BlendMultiply,
+			dst:      color.RGBA{},
+			src:      grey,
+			opacity:  1,
+			expected: grey,
+		},
+		{
+			mode:     BlendScreen,
+			dst:      grey,
+			src:      grey,
+			opacity:  1,
+			expected: color.RGBA{0xc0, 0xc0, 0xc0, 0xff},
+		},
+		{
+			mode:     BlendAdditive,
+			dst:      light,
+			src:      grey,
+			opacity:  1,
+			expected: color.RGBA{0xff, 0xff, 0xff, 0xff},
+		},
+		{
+			mode:     BlendAdditive,
+			dst:      dark,
+			src:      dark,
+			opacity:  1,
+			expected: color.RGBA{0x80, 0x80, 0x80, 0xff},
+		},
+		{
+			mode:     BlendOverlay,
+			dst:      dark,
+			src:      grey,
+			opacity:  1,
+			expected: color.RGBA{0x40, 0x40, 0x40, 0xff},
+		},
+		{
+			mode:     BlendOverlay,
+			dst:      light,
+			src:      grey,
+			opacity:  1,
+			expected: color.RGBA{0xc0, 0xc0, 0xc0, 0xff},
+		},
+	}
+
+	for _, test := range tests {
+		actual := test.mode.Blend(test.dst, test.src, test.opacity)
+		if !closeTo(actual, test.expected) {
+			t.Errorf("%v: blending %v onto %v at %v opacity, expected %v, got %v", test.mode, test.src, test.dst, test.opacity, test.expected, actual)
+		}
+	}
+}
+
+func TestCompositionOpacity(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	NewFilledRectangle(image.Point{0, 0}, 20, 20, colornames.White, colornames.White).Draw(img)
+
+	composition := NewComposition(image.Point{5, 5},
+		NewFilledRectangle(image.Point{0, 0}, 5, 5, colornames.Black, colornames.Black))
+	composition.Transparency = 0.5
+	composition.Draw(img)
+
+	expected := color.RGBA{0x80, 0x80, 0x80, 0xff}
+	if !closeTo(img.RGBAAt(7, 7), expected) {
+		t.Errorf("{7, 7}: expected half black over white to be %v, got %v", expected, img.At(7, 7))
+	}
+	if img.At(0, 0) != colornames.White {
+		t.Errorf("{0, 0}: expected the background to be untouched, got %v", img.At(0, 0))
+	}
+}
+
+func TestThatCompositionLiteralsAreOpaque(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	NewFilledRectangle(image.Point{0, 0}, 20, 20, colornames.White, colornames.White).Draw(img)
+
+	composition := &Composition{
+		Position:       image.Point{5, 5},
+		Components:     []Composable{NewFilledRectangle(image.Point{0, 0}, 5, 5, colornames.Black, colornames.Black)},
+		Transformation: affine.NewTransformation(affine.IdentityMatrix),
+	}
+	composition.Draw(img)
+
+	if img.At(7, 7) != colornames.Black {
+		t.Errorf("{7, 7}: expected the composition to be drawn opaque, got %v", img.At(7, 7))
+	}
+}
+
+func TestCompositionBlendMode(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	NewFilledRectangle(image.Point{0, 0}, 20, 20, colornames.Red, colornames.Red).Draw(img)
+
+	composition := NewComposition(image.Point{5, 5},
+		NewFilledRectangle(image.Point{0, 0}, 5, 5, colornames.Lime, colornames.Lime))
+	composition.BlendMode = BlendAdditive
+	composition.Draw(img)
+
+	expected := color.RGBA{0xff, 0xff, 0, 0xff}
+	if !closeTo(img.RGBAAt(7, 7), expected) {
+		t.Errorf("{7, 7}: expected red plus lime to be %v, got %v", expected, img.At(7, 7))
+	}
+}
+
+func closeTo(a, b color.RGBA) bool {
+	near := func(x, y uint8) bool {
+		d := int(x) - int(y)
+		return d >= -1 && d <= 1
+	}
+	return near(a.R, b.R) && near(a.G, b.G) && near(a.B, b.B) && near(a.A, b.A)
+}
@@ -0,0 +1,185 @@
+// Package camera maps a world onto the screen, so that it can be scrolled, zoomed and rotated
+// to follow the action.
+package camera
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/a-h/raster/actor"
+	"github.com/a-h/raster/affine"
+	"github.com/a-h/raster/sparse"
+)
+
+// Camera looks at part of a world which can be larger than the screen, so that it can be
+// scrolled, zoomed and rotated.
+type Camera struct {
+	// Position is the point in the world which is shown at the center of the viewport.
+	Position image.Point
+	// Zoom scales the world, e.g. 2 shows everything at twice the size.
+	Zoom float64
+	// Rotation rotates the camera by the number of degrees, so the world appears to rotate
+	// in the opposite direction.
+	Rotation float64
+	// Viewport is the area of the screen which the camera draws to.
+	Viewport image.Rectangle
+	// Bounds limits the movement of the camera, so that it doesn't show anything outside
+	// of the world. An empty rectangle allows the camera to move anywhere.
+	Bounds image.Rectangle
+	// DeadZone is the area, relative to the Position, which a followed point can move
+	// around in without the camera moving. An empty rectangle keeps the point centered.
+	DeadZone image.Rectangle
+}
+
+// New creates a camera which draws to the viewport, looking at the center of the viewport.
+func New(viewport image.Rectangle) *Camera {
+	return &Camera{
+		Position: center(viewport),
+		Zoom:     1,
+		Viewport: viewport,
+	}
+}
+
+func center(r image.Rectangle) image.Point {
+	return image.Point{r.Min.X + r.Dx()/2, r.Min.Y + r.Dy()/2}
+}
+
+// Scroll returns how far the camera has moved from the center of the viewport, e.g. to
+// scroll backgrounds with the camera.
+func (c *Camera) Scroll() image.Point {
+	return c.Position.Sub(center(c.Viewport))
+}
+
+// Transformation returns the transformation which maps world coordinates to screen
+// coordinates.
+func (c *Camera) Transformation() affine.Transformation {
+	toScreen := affine.NewTranslationTransformation(center(c.Viewport).X, center(c.Viewport).Y)
+	rotate := affine.NewRotationTransformation(-c.Rotation)
+	zoom := affine.NewScaleTransformation(c.Zoom, c.Zoom)
+	fromWorld := affine.NewTranslationTransformation(-c.Position.X, -c.Position.Y)
+	return toScreen.Combine(rotate).Combine(zoom).Combine(fromWorld)
+}
+
+// WorldToScreen returns the position on the screen of a point in the world.
+func (c *Camera) WorldToScreen(p image.Point) image.Point {
+	return c.Transformation().Apply(p)
+}
+
+// ScreenToWorld returns the point in the world which is shown at the position on the
+// screen, e.g. to find out what's been clicked on. An error is returned if the Zoom is 0.
+func (c *Camera) ScreenToWorld(p image.Point) (image.Point, error) {
+	inverse, err := c.Transformation().Inverse()
+	if err != nil {
+		return p, err
+	}
+	return inverse.Apply(p), nil
+}
+
+// VisibleBounds returns the area of the world which can be seen by the camera. When the
+// camera is rotated, the area contains the whole of the rotated viewport.
+func (c *Camera) VisibleBounds() image.Rectangle {
+	inverse, err := c.Transformation().Inverse()
+	if err != nil {
+		return image.Rectangle{}
+	}
+	return affine.TransformedBounds(inverse, c.Viewport)
+}
+
+// Follow moves the camera so that the point is within the dead zone, then clamps the
+// camera to its bounds.
+func (c *Camera) Follow(target image.Point) {
+	if c.DeadZone.Empty() {
+		c.Position = target
+		c.Clamp()
+		return
+	}
+	zone := c.DeadZone.Add(c.Position)
+	if target.X < zone.Min.X {
+		c.Position.X += target.X - zone.Min.X
+	}
+	if target.X >= zone.Max.X {
+		c.Position.X += target.X - (zone.Max.X - 1)
+	}
+	if target.Y < zone.Min.Y {
+		c.Position.Y += target.Y - zone.Min.Y
+	}
+	if target.Y >= zone.Max.Y {
+		c.Position.Y += target.Y - (zone.Max.Y - 1)
+	}
+	c.Clamp()
+}
+
+// FollowActor follows the center of the actor's composition.
+func (c *Camera) FollowActor(a actor.Actor) {
+	composition := a.Composition()
+	b := composition.Bounds()
+	c.Follow(composition.Position.Add(image.Point{b.Dx() / 2, b.Dy() / 2}))
+}
+
+// Clamp moves the camera so that it doesn't show anything outside of its Bounds. If the
+// Bounds are smaller than the area which the camera can see, the camera is centered on
+// them. Rotation is ignored.
+func (c *Camera) Clamp() {
+	if c.Bounds.Empty() || c.Zoom <= 0 {
+		return
+	}
+	halfWidth := float64(c.Viewport.Dx()) / 2 / c.Zoom
+	halfHeight := float64(c.Viewport.Dy()) / 2 / c.Zoom
+	c.Position.X = clampAxis(c.Position.X, c.Bounds.Min.X, c.Bounds.Max.X, halfWidth)
+	c.Position.Y = clampAxis(c.Position.Y, c.Bounds.Min.Y, c.Bounds.Max.Y, halfHeight)
+}
+
+func clampAxis(v, min, max int, half float64) int {
+	if float64(max-min) <= half*2 {
+		return min + (max-min)/2
+	}
+	low := int(math.Ceil(float64(min) + half))
+	high := int(math.Floor(float64(max) - half))
+	if v < low {
+		return low
+	}
+	if v > high {
+		return high
+	}
+	return v
+}
+
+// Draw draws the pixels of the world image src onto the dst image, as seen by the camera.
+// Only the pixels within the Viewport are drawn. It returns the area drawn on the dst image.
+func (c *Camera) Draw(dst draw.Image, src *sparse.Image) image.Rectangle {
+	t := c.Transformation()
+	area := affine.TransformedBounds(t, src.DrawnBounds()).Intersect(c.Viewport)
+	if area.Empty() {
+		return image.Rectangle{}
+	}
+
+	// When the camera is only scrolling, the pixels can be moved without sampling.
+	if c.Zoom == 1 && c.Rotation == 0 {
+		offset := t.Apply(image.Point{})
+		src.Each(func(x, y int, col color.RGBA) bool {
+			p := image.Point{x + offset.X, y + offset.Y}
+			if p.In(c.Viewport) {
+				dst.Set(p.X, p.Y, col)
+			}
+			return true
+		})
+		return area
+	}
+
+	// Otherwise, map each screen pixel back to the world, so that there are no gaps.
+	inverse, err := t.Inverse()
+	if err != nil {
+		return image.Rectangle{}
+	}
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			wx, wy := inverse.ApplyFloat(float64(x)+0.5, float64(y)+0.5)
+			if col, ok := src.Lookup(int(math.Floor(wx)), int(math.Floor(wy))); ok {
+				dst.Set(x, y, col)
+			}
+		}
+	}
+	return area
+}
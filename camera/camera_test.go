@@ -0,0 +1,222 @@
+package camera
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/a-h/raster"
+	"github.com/a-h/raster/actor"
+	"github.com/a-h/raster/sparse"
+
+	"golang.org/x/image/colornames"
+)
+
+func TestWorldToScreenAndBack(t *testing.T) {
+	tests := []struct {
+		name     string
+		camera   *Camera
+		world    image.Point
+		expected image.Point
+	}{
+		{
+			name:     "a new camera maps the world directly to the screen",
+			camera:   New(image.Rect(0, 0, 100, 100)),
+			world:    image.Point{10, 20},
+			expected: image.Point{10, 20},
+		},
+		{
+			name:     "the position is shown at the center of the viewport",
+			camera:   &Camera{Position: image.Point{500, 500}, Zoom: 1, Viewport: image.Rect(0, 0, 100, 100)},
+			world:    image.Point{500, 500},
+			expected: image.Point{50, 50},
+		},
+		{
+			name:     "zooming in moves points away from the center",
+			camera:   &Camera{Position: image.Point{500, 500}, Zoom: 2, Viewport: image.Rect(0, 0, 100, 100)},
+			world:    image.Point{510, 490},
+			expected: image.Point{70, 30},
+		},
+		{
+			name:     "rotating the camera rotates the world the opposite way",
+			camera:   &Camera{Position: image.Point{0, 0}, Zoom: 1, Rotation: 90, Viewport: image.Rect(0, 0, 100, 100)},
+			world:    image.Point{0, 10},
+			expected: image.Point{60, 50},
+		},
+	}
+
+	for _, test := range tests {
+		actual := test.camera.WorldToScreen(test.world)
+		if actual != test.expected {
+			t.Errorf("%s: WorldToScreen: expected %v, got %v", test.name, test.expected, actual)
+		}
+		back, err := test.camera.ScreenToWorld(actual)
+		if err != nil {
+			t.Fatalf("%s: ScreenToWorld: unexpected error: %v", test.name, err)
+		}
+		if back != test.world {
+			t.Errorf("%s: ScreenToWorld: expected %v, got %v", test.name, test.world, back)
+		}
+	}
+}
+
+func TestScreenToWorldWithoutZoom(t *testing.T) {
+	c := New(image.Rect(0, 0, 100, 100))
+	c.Zoom = 0
+	if _, err := c.ScreenToWorld(image.Point{}); err == nil {
+		t.Errorf("expected an error when the zoom is 0")
+	}
+}
+
+func TestVisibleBounds(t *testing.T) {
+	c := &Camera{Position: image.Point{500, 500}, Zoom: 2, Viewport: image.Rect(0, 0, 100, 50)}
+	expected := image.Rect(475, 487, 525, 513)
+	if actual := c.VisibleBounds(); actual != expected {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	tests := []struct {
+		name     string
+		deadZone image.Rectangle
+		target   image.Point
+		expected image.Point
+	}{
+		{
+			name:     "without a dead zone, the target is centered",
+			target:   image.Point{300, 400},
+			expected: image.Point{300, 400},
+		},
+		{
+			name:     "within the dead zone, the camera doesn't move",
+			deadZone: image.Rect(-20, -10, 20, 10),
+			target:   image.Point{515, 505},
+			expected: image.Point{500, 500},
+		},
+		{
+			name:     "outside the dead zone, the camera moves just enough",
+			deadZone: image.Rect(-20, -10, 20, 10),
+			target:   image.Point{530, 480},
+			expected: image.Point{511, 490},
+		},
+	}
+
+	for _, test := range tests {
+		c := &Camera{Position: image.Point{500, 500}, Zoom: 1, Viewport: image.Rect(0, 0, 100, 100), DeadZone: test.deadZone}
+		c.Follow(test.target)
+		if c.Position != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, c.Position)
+		}
+	}
+}
+
+func TestFollowActor(t *testing.T) {
+	c := New(image.Rect(0, 0, 100, 100))
+	a := actor.CompositionActor{
+		S: &actor.State{},
+		C: raster.NewComposition(image.Point{200, 300}, raster.NewSquare(image.Point{0, 0}, 10, colornames.Red)),
+	}
+	c.FollowActor(a)
+	// The composition's bounds are 11x11, so the center is 5 pixels in.
+	expected := image.Point{205, 305}
+	if c.Position != expected {
+		t.Errorf("expected %v, got %v", expected, c.Position)
+	}
+}
+
+func TestClamp(t *testing.T) {
+	tests := []struct {
+		name     string
+		bounds   image.Rectangle
+		zoom     float64
+		position image.Point
+		expected image.Point
+	}{
+		{
+			name:     "no bounds",
+			zoom:     1,
+			position: image.Point{-500, -500},
+			expected: image.Point{-500, -500},
+		},
+		{
+			name:     "top left",
+			bounds:   image.Rect(0, 0, 1000, 1000),
+			zoom:     1,
+			position: image.Point{0, 10},
+			expected: image.Point{50, 50},
+		},
+		{
+			name:     "bottom right",
+			bounds:   image.Rect(0, 0, 1000, 1000),
+			zoom:     1,
+			position: image.Point{2000, 990},
+			expected: image.Point{950, 950},
+		},
+		{
+			name:     "zooming in allows the camera closer to the edge",
+			bounds:   image.Rect(0, 0, 1000, 1000),
+			zoom:     2,
+			position: image.Point{0, 0},
+			expected: image.Point{25, 25},
+		},
+		{
+			name:     "a world smaller than the view is centered",
+			bounds:   image.Rect(0, 0, 60, 1000),
+			zoom:     1,
+			position: image.Point{0, 500},
+			expected: image.Point{30, 500},
+		},
+	}
+
+	for _, test := range tests {
+		c := &Camera{Position: test.position, Zoom: test.zoom, Viewport: image.Rect(0, 0, 100, 100), Bounds: test.bounds}
+		c.Clamp()
+		if c.Position != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, c.Position)
+		}
+	}
+}
+
+func TestDraw(t *testing.T) {
+	world := sparse.NewImage(image.Rect(0, 0, 1000, 1000))
+	world.Set(500, 500, colornames.Red)
+	world.Set(0, 0, colornames.Blue)
+
+	t.Run("scrolling", func(t *testing.T) {
+		c := &Camera{Position: image.Point{500, 500}, Zoom: 1, Viewport: image.Rect(0, 0, 100, 100)}
+		dst := image.NewRGBA(c.Viewport)
+		c.Draw(dst, world)
+		if dst.RGBAAt(50, 50) != colornames.Red {
+			t.Errorf("expected the red pixel at the center, got %v", dst.RGBAAt(50, 50))
+		}
+		if dst.RGBAAt(0, 0) != (color.RGBA{}) {
+			t.Errorf("expected the blue pixel to be out of view, got %v", dst.RGBAAt(0, 0))
+		}
+	})
+
+	t.Run("zooming fills the gaps between pixels", func(t *testing.T) {
+		c := &Camera{Position: image.Point{500, 500}, Zoom: 2, Viewport: image.Rect(0, 0, 100, 100)}
+		dst := image.NewRGBA(c.Viewport)
+		area := c.Draw(dst, world)
+		for _, p := range []image.Point{{50, 50}, {51, 50}, {50, 51}, {51, 51}} {
+			if dst.RGBAAt(p.X, p.Y) != colornames.Red {
+				t.Errorf("expected %v to be red, got %v", p, dst.RGBAAt(p.X, p.Y))
+			}
+		}
+		if !image.Rect(50, 50, 52, 52).In(area) {
+			t.Errorf("expected the drawn area %v to contain the zoomed pixel", area)
+		}
+	})
+}
+
+func TestScroll(t *testing.T) {
+	c := New(image.Rect(0, 0, 100, 100))
+	if c.Scroll() != (image.Point{}) {
+		t.Errorf("expected a new camera not to be scrolled, got %v", c.Scroll())
+	}
+	c.Position = c.Position.Add(image.Point{10, -5})
+	if expected := (image.Point{10, -5}); c.Scroll() != expected {
+		t.Errorf("expected %v, got %v", expected, c.Scroll())
+	}
+}
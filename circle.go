@@ -25,6 +25,7 @@ func NewCircle(center image.Point, radius int, outlineColor color.RGBA) Circle {
 
 // Draw draws the element to the img, img could be an image.RGBA* or screen buffer.
 func (c Circle) Draw(img draw.Image) image.Rectangle {
+	set := Setter(img)
 	bounds := image.Rect(c.Center.X-c.Radius-2, c.Center.Y-c.Radius-2, c.Center.X+c.Radius+2, c.Center.Y+c.Radius+2)
 	for iy := bounds.Min.Y; iy < bounds.Max.Y; iy++ {
 		// Work out from the left.
@@ -37,7 +38,7 @@ func (c Circle) Draw(img draw.Image) image.Rectangle {
 			onRadius := int(distanceFromCenter) == c.Radius
 
 			if onRadius {
-				img.Set(ix, iy, c.OutlineColor)
+				set(ix, iy, c.OutlineColor)
 				foundBorder = true
 			}
 
@@ -56,7 +57,7 @@ func (c Circle) Draw(img draw.Image) image.Rectangle {
 			onRadius := int(distanceFromCenter) == c.Radius
 
 			if onRadius {
-				img.Set(ix, iy, c.OutlineColor)
+				set(ix, iy, c.OutlineColor)
 				foundBorder = true
 			}
 
@@ -2,12 +2,16 @@ package raster
 
 import (
 	"image"
+	"image/color"
 	"image/draw"
+	"math"
+	"reflect"
 
 	"github.com/a-h/raster/biggest"
 	"github.com/a-h/raster/smallest"
 
 	"github.com/a-h/raster/affine"
+	"github.com/a-h/raster/filter"
 	"github.com/a-h/raster/sparse"
 )
 
@@ -22,10 +26,30 @@ type Composable interface {
 // Composition returns the position and components which make it up, and a transformation
 // that can be applied to it to move, scale, or rotate all of the elements.
 type Composition struct {
-	Position       image.Point
-	Components     []Composable
-	cache          *sparse.Image
-	Transformation affine.Transformation
+	Position   image.Point
+	Components []Composable
+	cache      *sparse.Image
+	// cachedComponents identifies the Components slice used to build the cache, so that a
+	// new slice being assigned to the field can be detected without comparing the components.
+	cachedComponents sliceID
+	Transformation   affine.Transformation
+	// Projection is an optional perspective transform, applied after the Transformation,
+	// e.g. to flip a card over or lay the composition down as a floor.
+	Projection *affine.Projection
+	// Transparency of the composition when it's drawn, from 0 (opaque) to 1 (invisible).
+	// Storing transparency, rather than opacity, keeps compositions which don't set it
+	// opaque.
+	Transparency float64
+	// BlendMode defines how the composition's pixels are combined with the image.
+	BlendMode BlendMode
+	// Filters are applied, in order, to the composition's pixels before they're drawn.
+	Filters []filter.Filter
+	// filtered holds the cache after the filters have been applied.
+	filtered      *sparse.Image
+	cachedFilters sliceID
+	// dense holds the filtered pixels in an image.RGBA, which is faster to sample from when
+	// the composition is scaled or rotated.
+	dense *image.RGBA
 }
 
 // NewComposition creates a composition for rendering at the specific point. The components must
@@ -35,38 +59,233 @@ func NewComposition(position image.Point, components ...Composable) *Composition
 		Position:       position,
 		Components:     components,
 		Transformation: affine.NewTransformation(affine.IdentityMatrix),
+		BlendMode:      BlendNormal,
 	}
 }
 
 // Draw draws the element to the img, img could be an image.RGBA* or screen buffer.
 // It returns the area actually drawn out on the image.
 func (c *Composition) Draw(img draw.Image) image.Rectangle {
-	// Draw on a temporary canvas.
-	// Cache the base image.
-	if c.cache == nil {
-		c.cache = sparse.NewImage(c.Bounds())
-		for _, component := range c.Components {
-			component.Draw(c.cache)
+	c.prepare()
+
+	// Only blend when required, otherwise the pixels replace those on the image.
+	blend := c.blends()
+	set := Setter(img)
+
+	return c.each(func(x, y int, pixelColor color.RGBA) {
+		if blend {
+			img.Set(x, y, c.BlendMode.Blend(img.At(x, y), pixelColor, c.opacity()))
+			return
 		}
+		set(x, y, pixelColor)
+	})
+}
+
+// blends returns true if the composition's pixels are blended with the pixels which are
+// already on the image. Filters produce semi-transparent pixels (e.g. shadows), so they're
+// always blended.
+func (c *Composition) blends() bool {
+	return c.BlendMode != BlendNormal || c.Transparency > 0 || len(c.Filters) > 0
+}
+
+// opacity returns the opposite of the Transparency, from 0 (invisible) to 1 (opaque).
+func (c *Composition) opacity() float64 {
+	return 1 - c.Transparency
+}
+
+// each calls f with the position and color of each of the composition's pixels, after the
+// transformations have been applied, returning the area covered. The composition must have
+// been prepared.
+func (c *Composition) each(f func(x, y int, pixelColor color.RGBA)) image.Rectangle {
+	if c.Projection == nil && c.translates() {
+		return c.eachTranslated(f)
 	}
 
-	// Apply the composition's transformations each time.
-	minX, minY, maxX, maxY := c.Position.X, c.Position.Y, 0, 0
-	for position, color := range c.cache.Drawn {
-		transformedPoint := c.Transformation.Apply(position)
+	// Map each pixel of the image back to the cache, so that there are no gaps between the
+	// pixels when the composition is scaled up, rotated or projected.
+	inverse, ok := c.inverse()
+	if !ok {
+		// The composition has been squashed flat, so there's nothing to draw.
+		return image.Rectangle{}
+	}
+	src := c.dense
+	area := c.transformedBounds(src.Rect)
+	minX, minY, maxX, maxY := area.Max.X, area.Max.Y, area.Min.X-1, area.Min.Y-1
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			// Sample from the center of the pixel.
+			px, py, ok := inverse(float64(x-c.Position.X)+0.5, float64(y-c.Position.Y)+0.5)
+			if !ok {
+				continue
+			}
+			pixelColor := sample(src, int(math.Floor(px)), int(math.Floor(py)))
+			if pixelColor.A == 0 {
+				continue
+			}
+			minX = smallest.IntegerIn(minX, x)
+			maxX = biggest.IntegerIn(maxX, x)
+			minY = smallest.IntegerIn(minY, y)
+			maxY = biggest.IntegerIn(maxY, y)
+			f(x, y, pixelColor)
+		}
+	}
+	if maxX < minX {
+		return image.Rectangle{}
+	}
+	return image.Rect(minX, minY, maxX+1, maxY+1)
+}
 
+// forward maps a point of the cache to the image, relative to the composition's Position. It
+// returns false if the point is behind the viewer of the Projection.
+func (c *Composition) forward(x, y float64) (x1, y1 float64, ok bool) {
+	x1, y1 = c.Transformation.ApplyFloat(x, y)
+	if c.Projection == nil {
+		return x1, y1, true
+	}
+	return c.Projection.Project(x1, y1)
+}
+
+// inverse returns a function which maps a point of the image, relative to the composition's
+// Position, back to the cache. It returns false if the transformations can't be undone.
+func (c *Composition) inverse() (f func(x, y float64) (x1, y1 float64, ok bool), ok bool) {
+	transformation, err := c.Transformation.Inverse()
+	if err != nil {
+		return nil, false
+	}
+	if c.Projection == nil {
+		return func(x, y float64) (float64, float64, bool) {
+			x1, y1 := transformation.ApplyFloat(x, y)
+			return x1, y1, true
+		}, true
+	}
+	projection, err := c.Projection.Inverse()
+	if err != nil {
+		return nil, false
+	}
+	return func(x, y float64) (float64, float64, bool) {
+		px, py, ok := projection.Project(x, y)
+		if !ok {
+			return 0, 0, false
+		}
+		// Points behind the viewer are projected in front of them by the inverse, so check
+		// that the point really is in front.
+		if _, _, ok := c.Projection.Project(px, py); !ok {
+			return 0, 0, false
+		}
+		x1, y1 := transformation.ApplyFloat(px, py)
+		return x1, y1, true
+	}, true
+}
+
+// sampled returns the filtered pixels as a dense image, which covers the drawn pixels.
+func (c *Composition) sampled() *image.RGBA {
+	dense := image.NewRGBA(c.filtered.DrawnBounds())
+	c.filtered.EachSpan(func(s sparse.Span) bool {
+		for i, pixelColor := range s.Pix {
+			dense.SetRGBA(s.X+i, s.Y, pixelColor)
+		}
+		return true
+	})
+	return dense
+}
+
+// sample returns the color of the pixel, or a transparent color if it's outside the image.
+func sample(img *image.RGBA, x, y int) color.RGBA {
+	if !(image.Point{x, y}).In(img.Rect) {
+		return color.RGBA{}
+	}
+	return img.RGBAAt(x, y)
+}
+
+// translates returns true if the Transformation only moves the composition by whole pixels,
+// so that each pixel of the cache maps to exactly one pixel of the image.
+func (c *Composition) translates() bool {
+	m := c.Transformation.Matrix()
+	return m[0] == 1 && m[1] == 0 && m[3] == 0 && m[4] == 1 &&
+		m[2] == math.Trunc(m[2]) && m[5] == math.Trunc(m[5])
+}
+
+// eachTranslated is each for compositions which are only moved by whole pixels.
+func (c *Composition) eachTranslated(f func(x, y int, pixelColor color.RGBA)) image.Rectangle {
+	minX, minY, maxX, maxY := c.Position.X, c.Position.Y, 0, 0
+	c.filtered.Each(func(px, py int, pixelColor color.RGBA) bool {
+		transformedPoint := c.Transformation.Apply(image.Point{px, py})
 		x, y := transformedPoint.X+c.Position.X, transformedPoint.Y+c.Position.Y
 		minX = smallest.IntegerIn(minX, x)
 		maxX = biggest.IntegerIn(maxX, x)
 		minY = smallest.IntegerIn(minY, y)
 		maxY = biggest.IntegerIn(maxY, y)
 
-		img.Set(x, y, color)
-	}
+		f(x, y, pixelColor)
+		return true
+	})
 
 	return image.Rect(minX, minY, maxX+1, maxY+1)
 }
 
+// transformedBounds returns the area of the image covered by the area r of the cache.
+func (c *Composition) transformedBounds(r image.Rectangle) image.Rectangle {
+	if r.Empty() {
+		return image.Rectangle{}
+	}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	extend := func(x, y float64) {
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+	}
+	corners := [][2]int{{r.Min.X, r.Min.Y}, {r.Max.X, r.Min.Y}, {r.Max.X, r.Max.Y}, {r.Min.X, r.Max.Y}}
+	for _, corner := range corners {
+		x, y, ok := c.forward(float64(corner[0]), float64(corner[1]))
+		if !ok {
+			// Part of the composition is behind the viewer, so find the pixels which aren't.
+			return c.projectedBounds()
+		}
+		extend(x, y)
+	}
+	return image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY))).
+		Add(c.Position)
+}
+
+// projectedBounds returns the area of the image covered by the pixels of the cache which
+// are in front of the viewer.
+func (c *Composition) projectedBounds() image.Rectangle {
+	var bounds image.Rectangle
+	c.filtered.Each(func(px, py int, pixelColor color.RGBA) bool {
+		for _, corner := range [][2]int{{px, py}, {px + 1, py + 1}, {px + 1, py}, {px, py + 1}} {
+			if x, y, ok := c.forward(float64(corner[0]), float64(corner[1])); ok {
+				p := image.Pt(int(math.Floor(x)), int(math.Floor(y)))
+				bounds = bounds.Union(image.Rectangle{p, p.Add(image.Pt(1, 1))})
+			}
+		}
+		return true
+	})
+	return bounds.Add(c.Position)
+}
+
+// prepare draws the components onto a temporary canvas, and applies the filters. The
+// results are cached, and only rebuilt if the components or filters have changed. Nothing
+// is cached while drawing, so a prepared composition can be drawn from multiple goroutines.
+func (c *Composition) prepare() {
+	if c.cache == nil || c.cachedComponents != componentsID(c.Components) {
+		c.cache = sparse.NewImage(c.Bounds())
+		for _, component := range c.Components {
+			component.Draw(c.cache)
+		}
+		c.cachedComponents = componentsID(c.Components)
+		c.filtered = nil
+	}
+	if c.filtered == nil || c.cachedFilters != filtersID(c.Filters) {
+		c.filtered = filter.Apply(c.cache, c.Filters...)
+		c.dense = nil
+		c.cachedFilters = filtersID(c.Filters)
+	}
+	// Compositions which are only moved are drawn from the filtered pixels directly.
+	if c.dense == nil && (c.Projection != nil || !c.translates()) {
+		c.dense = c.sampled()
+	}
+}
+
 // Bounds provides the area of the composition prior to affine transformations being
 // applied.
 func (c *Composition) Bounds() image.Rectangle {
@@ -84,3 +303,131 @@ func (c *Composition) Bounds() image.Rectangle {
 
 	return image.Rect(0, 0, maxX+1, maxY+1)
 }
+
+// Invalidate discards the cached pixels of the composition, so that the components are
+// redrawn the next time the composition is drawn. Assigning a new slice to the Components
+// or Filters fields is detected automatically, but changing the elements of the slices, or
+// a component itself (e.g. its color), is not, so Invalidate must be called afterwards.
+func (c *Composition) Invalidate() {
+	c.cache = nil
+	c.cachedComponents = sliceID{}
+	c.filtered = nil
+	c.cachedFilters = sliceID{}
+	c.dense = nil
+}
+
+// Add adds components to the top of the composition.
+func (c *Composition) Add(components ...Composable) {
+	c.Components = append(c.Components, components...)
+	c.Invalidate()
+}
+
+// Remove removes the component from the composition. It returns false if the component
+// is not part of the composition.
+func (c *Composition) Remove(component Composable) bool {
+	i := c.indexOf(component)
+	if i < 0 {
+		return false
+	}
+	c.Components = append(c.Components[:i:i], c.Components[i+1:]...)
+	c.Invalidate()
+	return true
+}
+
+// Replace swaps the old component for the new one, keeping its position in the drawing
+// order. It returns false if the old component is not part of the composition.
+func (c *Composition) Replace(old, new Composable) bool {
+	i := c.indexOf(old)
+	if i < 0 {
+		return false
+	}
+	components := append([]Composable(nil), c.Components...)
+	components[i] = new
+	c.Components = components
+	c.Invalidate()
+	return true
+}
+
+// Move moves the component at index from to index to, shifting the components in between.
+// Components are drawn in order, so components with a higher index are drawn on top.
+func (c *Composition) Move(from, to int) {
+	if from < 0 || from >= len(c.Components) || to < 0 || to >= len(c.Components) {
+		return
+	}
+	components := append([]Composable(nil), c.Components...)
+	component := components[from]
+	if from < to {
+		copy(components[from:to], components[from+1:to+1])
+	} else {
+		copy(components[to+1:from+1], components[to:from])
+	}
+	components[to] = component
+	c.Components = components
+	c.Invalidate()
+}
+
+// BringToFront moves the component so that it's drawn on top of all of the others. It
+// returns false if the component is not part of the composition.
+func (c *Composition) BringToFront(component Composable) bool {
+	i := c.indexOf(component)
+	if i < 0 {
+		return false
+	}
+	c.Move(i, len(c.Components)-1)
+	return true
+}
+
+// SendToBack moves the component so that it's drawn underneath all of the others. It
+// returns false if the component is not part of the composition.
+func (c *Composition) SendToBack(component Composable) bool {
+	i := c.indexOf(component)
+	if i < 0 {
+		return false
+	}
+	c.Move(i, 0)
+	return true
+}
+
+func (c *Composition) indexOf(component Composable) int {
+	for i, existing := range c.Components {
+		if same(existing, component) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sliceID identifies a slice by the address of its first element and its length.
+type sliceID struct {
+	first interface{}
+	len   int
+}
+
+func componentsID(components []Composable) sliceID {
+	if len(components) == 0 {
+		return sliceID{}
+	}
+	return sliceID{first: &components[0], len: len(components)}
+}
+
+func filtersID(filters []filter.Filter) sliceID {
+	if len(filters) == 0 {
+		return sliceID{}
+	}
+	return sliceID{first: &filters[0], len: len(filters)}
+}
+
+// same compares values such as components, some of which (e.g. Polygon) contain
+// slices and so can't be compared with ==.
+func same(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+	if reflect.TypeOf(a).Comparable() {
+		return a == b
+	}
+	return reflect.DeepEqual(a, b)
+}
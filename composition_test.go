@@ -2,9 +2,12 @@ package raster
 
 import (
 	"image"
+	"image/color"
+	"reflect"
 	"testing"
 
 	"github.com/a-h/raster/affine"
+	"github.com/a-h/raster/filter"
 
 	"golang.org/x/image/colornames"
 )
@@ -92,12 +95,325 @@ func TestCompositionWithAffineTransformation(t *testing.T) {
 	if img2.At(topRight.X, topRight.Y) != colornames.White {
 		t.Errorf("Top right corner was not in correct position")
 	}
-	bottomLeft := image.Point{24, 14}
+	// The rotated square covers y=5.76 to y=14.24, centered on y=10. Pixels are drawn if
+	// their centers are covered, which leaves row 14 empty.
+	bottomLeft := image.Point{24, 13}
 	if img2.At(bottomLeft.X, bottomLeft.Y) != colornames.White {
 		t.Errorf("Bottom left corner was not in correct position")
 	}
-	bottomRight := image.Point{31, 14}
+	bottomRight := image.Point{31, 13}
 	if img2.At(bottomRight.X, bottomRight.Y) != colornames.White {
 		t.Errorf("Bottom right corner was not in correct position")
 	}
 }
+
+func TestThatScalingUpLeavesNoGaps(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	composition := NewComposition(image.Point{5, 5},
+		NewFilledRectangle(image.Point{0, 0}, 9, 9, colornames.Red, colornames.Red))
+	composition.Transformation = affine.NewScaleTransformation(3, 3)
+	area := composition.Draw(img)
+
+	expected := image.Rect(5, 5, 35, 35)
+	if area != expected {
+		t.Errorf("expected the drawn area to be %v, got %v", expected, area)
+	}
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 40; x++ {
+			filled := img.RGBAAt(x, y) == colornames.Red
+			if shouldBeFilled := (image.Point{x, y}).In(expected); filled != shouldBeFilled {
+				t.Fatalf("{%d, %d}: expected filled to be %v, got %v", x, y, shouldBeFilled, filled)
+			}
+		}
+	}
+}
+
+func TestThatChangingComponentsInvalidatesTheCache(t *testing.T) {
+	composition := NewComposition(image.Point{0, 0},
+		NewSquare(image.Point{0, 0}, 10, colornames.Green))
+
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	composition.Draw(img)
+	if img.At(0, 0) != colornames.Green {
+		t.Errorf("{0, 0}: expected green, got %v", img.At(0, 0))
+	}
+
+	// Replacing the field directly should be detected.
+	composition.Components = []Composable{NewSquare(image.Point{0, 0}, 10, colornames.Red)}
+	img = image.NewRGBA(image.Rect(0, 0, 20, 20))
+	composition.Draw(img)
+	if img.At(0, 0) != colornames.Red {
+		t.Errorf("{0, 0}: after changing the components, expected red, got %v", img.At(0, 0))
+	}
+}
+
+func TestThatChangingAComponentRequiresInvalidation(t *testing.T) {
+	line := NewLine(image.Point{0, 0}, image.Point{10, 0}, colornames.Green)
+	composition := NewComposition(image.Point{0, 0}, line)
+
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	composition.Draw(img)
+
+	line.OutlineColor = colornames.Red
+	composition.Draw(img)
+	if img.At(0, 0) != colornames.Green {
+		t.Errorf("{0, 0}: before invalidation, expected the cached green, got %v", img.At(0, 0))
+	}
+
+	composition.Invalidate()
+	composition.Draw(img)
+	if img.At(0, 0) != colornames.Red {
+		t.Errorf("{0, 0}: after invalidation, expected red, got %v", img.At(0, 0))
+	}
+}
+
+func TestThatChangingTheElementsOfComponentsRequiresInvalidation(t *testing.T) {
+	composition := NewComposition(image.Point{0, 0},
+		NewSquare(image.Point{0, 0}, 10, colornames.Green))
+	composition.Draw(image.NewRGBA(image.Rect(0, 0, 20, 20)))
+
+	composition.Components[0] = NewSquare(image.Point{0, 0}, 10, colornames.Red)
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	composition.Draw(img)
+	if img.At(0, 0) != colornames.Green {
+		t.Errorf("{0, 0}: expected the cached green square, got %v", img.At(0, 0))
+	}
+
+	composition.Invalidate()
+	composition.Draw(img)
+	if img.At(0, 0) != colornames.Red {
+		t.Errorf("{0, 0}: after invalidating, expected red, got %v", img.At(0, 0))
+	}
+}
+
+func TestCompositionMutation(t *testing.T) {
+	red := NewSquare(image.Point{0, 0}, 10, colornames.Red)
+	green := NewSquare(image.Point{0, 0}, 10, colornames.Green)
+	blue := NewSquare(image.Point{0, 0}, 10, colornames.Blue)
+	white := NewPolygon(colornames.White, image.Point{0, 0}, image.Point{10, 0}, image.Point{10, 10})
+
+	tests := []struct {
+		name     string
+		mutate   func(c *Composition)
+		expected []Composable
+	}{
+		{
+			name:     "add",
+			mutate:   func(c *Composition) { c.Add(blue) },
+			expected: []Composable{red, green, blue},
+		},
+		{
+			name: "remove",
+			mutate: func(c *Composition) {
+				if !c.Remove(green) {
+					t.Errorf("remove: expected green to be found")
+				}
+			},
+			expected: []Composable{red},
+		},
+		{
+			name: "remove missing",
+			mutate: func(c *Composition) {
+				if c.Remove(blue) {
+					t.Errorf("remove missing: expected blue not to be found")
+				}
+			},
+			expected: []Composable{red, green},
+		},
+		{
+			name: "replace",
+			mutate: func(c *Composition) {
+				if !c.Replace(red, white) {
+					t.Errorf("replace: expected red to be found")
+				}
+			},
+			expected: []Composable{white, green},
+		},
+		{
+			name: "bring to front",
+			mutate: func(c *Composition) {
+				c.Add(blue)
+				c.BringToFront(red)
+			},
+			expected: []Composable{green, blue, red},
+		},
+		{
+			name: "send to back",
+			mutate: func(c *Composition) {
+				c.Add(blue)
+				c.SendToBack(blue)
+			},
+			expected: []Composable{blue, red, green},
+		},
+		{
+			name: "move",
+			mutate: func(c *Composition) {
+				c.Add(blue)
+				c.Move(0, 1)
+			},
+			expected: []Composable{green, red, blue},
+		},
+	}
+
+	for _, test := range tests {
+		composition := NewComposition(image.Point{0, 0}, red, green)
+		img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+		composition.Draw(img)
+
+		test.mutate(composition)
+
+		if !reflect.DeepEqual(composition.Components, test.expected) {
+			t.Errorf("%s: expected components %v, got %v", test.name, test.expected, composition.Components)
+			continue
+		}
+
+		// The top component should be drawn.
+		img = image.NewRGBA(image.Rect(0, 0, 20, 20))
+		composition.Draw(img)
+		top := test.expected[len(test.expected)-1]
+		expected := image.NewRGBA(image.Rect(0, 0, 20, 20))
+		top.Draw(expected)
+		if img.At(10, 0) != expected.At(10, 0) {
+			t.Errorf("%s: expected the top component to be drawn with %v, got %v", test.name, expected.At(10, 0), img.At(10, 0))
+		}
+	}
+}
+
+func TestCompositionFilters(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	NewFilledRectangle(image.Point{0, 0}, 40, 40, colornames.White, colornames.White).Draw(img)
+
+	composition := NewComposition(image.Point{10, 10},
+		NewFilledRectangle(image.Point{0, 0}, 10, 10, colornames.Red, colornames.Red))
+	composition.Filters = []filter.Filter{
+		filter.DropShadow{Offset: image.Point{5, 5}, Color: colornames.Black},
+	}
+	composition.Draw(img)
+
+	if img.At(12, 12) != colornames.Red {
+		t.Errorf("{12, 12}: expected red, got %v", img.At(12, 12))
+	}
+	if img.At(22, 22) != colornames.Black {
+		t.Errorf("{22, 22}: expected the shadow, got %v", img.At(22, 22))
+	}
+
+	// Changing the filters should be detected.
+	composition.Filters = nil
+	img = image.NewRGBA(image.Rect(0, 0, 40, 40))
+	composition.Draw(img)
+	if img.At(22, 22) != (color.RGBA{}) {
+		t.Errorf("{22, 22}: after removing the filter, expected no shadow, got %v", img.At(22, 22))
+	}
+}
+
+func TestCompositionProjection(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+
+	composition := NewComposition(image.Point{0, 0},
+		NewLine(image.Point{0, 0}, image.Point{20, 0}, colornames.White))
+	projection, err := affine.NewProjectionFromPoints(
+		[4]image.Point{{0, 0}, {20, 0}, {20, 20}, {0, 20}},
+		[4]image.Point{{10, 5}, {30, 5}, {40, 25}, {0, 25}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	composition.Projection = &projection
+	composition.Draw(img)
+
+	if img.At(10, 5) != colornames.White {
+		t.Errorf("{10, 5}: expected the start of the line, got %v", img.At(10, 5))
+	}
+	if img.At(30, 5) != colornames.White {
+		t.Errorf("{30, 5}: expected the end of the line, got %v", img.At(30, 5))
+	}
+	if img.At(0, 0) == colornames.White {
+		t.Errorf("{0, 0}: expected the line to have moved")
+	}
+}
+
+func TestThatMagnifyingProjectionsLeaveNoGaps(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 50, 50))
+	composition := NewComposition(image.Point{0, 0},
+		NewFilledRectangle(image.Point{0, 0}, 9, 9, colornames.Red, colornames.Red))
+	// Stretch the 10x10 square to a 40x40 square.
+	projection, err := affine.NewProjectionFromPoints(
+		[4]image.Point{{0, 0}, {10, 0}, {10, 10}, {0, 10}},
+		[4]image.Point{{0, 0}, {40, 0}, {40, 40}, {0, 40}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	composition.Projection = &projection
+	composition.Draw(img)
+
+	expected := image.Rect(0, 0, 40, 40)
+	for y := 0; y < 50; y++ {
+		for x := 0; x < 50; x++ {
+			filled := img.RGBAAt(x, y) == colornames.Red
+			if shouldBeFilled := (image.Point{x, y}).In(expected); filled != shouldBeFilled {
+				t.Fatalf("{%d, %d}: expected filled to be %v, got %v", x, y, shouldBeFilled, filled)
+			}
+		}
+	}
+}
+
+func TestThatProjectedPointsBehindTheViewerAreNotDrawn(t *testing.T) {
+	img := image.NewRGBA(image.Rect(-100, -100, 100, 100))
+	composition := NewComposition(image.Point{0, 0},
+		NewFilledRectangle(image.Point{0, 0}, 20, 20, colornames.Red, colornames.Red))
+	// Turn the composition so that its right hand side is behind the viewer.
+	projection := affine.NewYRotationProjection(-60, image.Point{0, 10}, 10)
+	composition.Projection = &projection
+	area := composition.Draw(img)
+
+	if area.Empty() {
+		t.Fatalf("expected the part of the composition in front of the viewer to be drawn")
+	}
+	if img.RGBAAt(1, 10) != colornames.Red {
+		t.Errorf("{1, 10}: expected the pixel next to the pivot to be drawn, got %v", img.RGBAAt(1, 10))
+	}
+	if img.RGBAAt(-1, 10) == colornames.Red {
+		t.Errorf("{-1, 10}: expected the points behind the viewer not to be drawn")
+	}
+}
+
+func BenchmarkComposition(b *testing.B) {
+	img := image.NewRGBA(image.Rect(0, 0, 1000, 1000))
+	composition := NewComposition(image.Point{250, 250},
+		NewFilledCircle(image.Point{250, 250}, 250, colornames.Maroon, colornames.Antiquewhite))
+	for i := 0; i < b.N; i++ {
+		composition.Transformation = affine.NewRotationAboutTransformation(float64(i), image.Point{250, 250})
+		composition.Draw(img)
+	}
+}
+
+func BenchmarkCompositionCache(b *testing.B) {
+	img := image.NewRGBA(image.Rect(0, 0, 1000, 1000))
+	for i := 0; i < b.N; i++ {
+		composition := NewComposition(image.Point{250, 250},
+			NewFilledCircle(image.Point{250, 250}, 250, colornames.Maroon, colornames.Antiquewhite))
+		composition.Draw(img)
+	}
+}
+
+func TestThatBlendedCompositionsAreDeterministic(t *testing.T) {
+	composition := NewComposition(image.Point{0, 0},
+		NewFilledRectangle(image.Point{0, 0}, 20, 20, colornames.Red, colornames.Blue),
+		NewLine(image.Point{0, 0}, image.Point{20, 20}, colornames.Yellow))
+	// Scaling down maps several pixels onto each pixel of the image, so the order in which
+	// they're blended affects the result.
+	composition.Transformation = affine.NewScaleTransformation(0.5, 0.5)
+	composition.Transparency = 0.5
+
+	draw := func() *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+		composition.Draw(img)
+		return img
+	}
+
+	expected := draw()
+	for i := 0; i < 20; i++ {
+		if actual := draw(); !reflect.DeepEqual(actual.Pix, expected.Pix) {
+			t.Fatal("expected the composition to be drawn the same way each time")
+		}
+	}
+}
@@ -49,9 +49,10 @@ func main() {
 			circle := raster.NewCircle(image.Point{i + 50, i + 50}, 50, colornames.Red)
 			circle.Draw(stg.NextFrame)
 
-			// Draw the whole stage.
-			stg.Draw(img)
-			w.Upload(image.Point{0, 0}, background, image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+			// Draw the stage, and upload only the areas which have changed.
+			for _, r := range stg.Draw(img) {
+				w.Upload(r.Min, background, r)
+			}
 			w.Publish()
 		}
 
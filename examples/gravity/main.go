@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"image"
 	"image/draw"
@@ -84,8 +85,9 @@ func run() {
 	}
 
 	logrus.Debugf("starting world")
-	stopper := make(<-chan bool)
-	nw.Run(stopper)
+	if err := nw.Run(context.Background()); err != nil {
+		logrus.Errorf("world stopped: %v", err)
+	}
 	wde.Stop()
 }
 
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"image"
 	"image/draw"
+	"time"
 
 	"github.com/a-h/raster/affine"
 	"github.com/a-h/raster/stage"
+	"github.com/a-h/raster/tween"
 
 	"github.com/a-h/raster"
 
@@ -47,20 +49,26 @@ func main() {
 		square := raster.NewPolygon(colornames.Lightgrey, image.Point{0, 0}, image.Point{100, 0}, image.Point{100, 100}, image.Point{0, 100})
 		c := raster.NewComposition(image.Point{500 - 50, 500 - 50}, triangle, square)
 
-		for i := 0; i <= 1000; i += 5 {
+		// Spin the triangle around for 4 seconds at 50 frames per second, slowing down at the end.
+		spin := tween.New(time.Second*4, tween.EaseOutCubic)
+		for {
+			// Rotate the triangle from the center.
+			degrees := tween.Float(0, 1000, spin.Progress())
+			c.Transformation = affine.NewRotationAboutTransformation(degrees, image.Point{50, 50})
+
 			// Draw the items on the next frame of the stage.
 			c.Draw(stg.NextFrame)
 
-			// Draw the whole stage.
-			stg.Draw(img)
-			w.Upload(image.Point{0, 0}, background, image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+			// Draw the stage, and upload only the areas which have changed.
+			for _, r := range stg.Draw(img) {
+				w.Upload(r.Min, background, r)
+			}
 			w.Publish()
 
-			// Rotate the triangle from the center.
-			moveToCenter := affine.NewTranslationTransformation(50, 50)
-			rotate := affine.NewRotationTransformation(float64(i))
-			moveBack := affine.NewTranslationTransformation(-50, -50)
-			c.Transformation = moveToCenter.Combine(rotate).Combine(moveBack)
+			if spin.Done() {
+				break
+			}
+			spin.Advance(time.Second / 50)
 		}
 
 		// Keep looking for events.
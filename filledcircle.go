@@ -26,6 +26,7 @@ func NewFilledCircle(center image.Point, radius int, outlineColor color.RGBA, fi
 
 // Draw draws the element to the img, img could be an image.RGBA* or screen buffer.
 func (c FilledCircle) Draw(img draw.Image) image.Rectangle {
+	set := Setter(img)
 	bounds := image.Rect(c.Center.X-c.Radius-2, c.Center.Y-c.Radius-2, c.Center.X+c.Radius+2, c.Center.Y+c.Radius+2)
 	for ix := bounds.Min.X; ix < bounds.Max.X; ix++ {
 		for iy := bounds.Min.Y; iy < bounds.Max.Y; iy++ {
@@ -34,10 +35,10 @@ func (c FilledCircle) Draw(img draw.Image) image.Rectangle {
 
 			distanceFromCenter := math.Sqrt(float64(((width * width) + (height * height))))
 			if int(distanceFromCenter) == c.Radius {
-				img.Set(ix, iy, c.OutlineColor)
+				set(ix, iy, c.OutlineColor)
 			}
 			if int(distanceFromCenter) < c.Radius {
-				img.Set(ix, iy, c.FillColor)
+				set(ix, iy, c.FillColor)
 			}
 		}
 	}
@@ -55,6 +55,7 @@ func (p FilledPolygon) Bounds() image.Rectangle {
 
 // Draw draws the filled polygon onto the image.
 func (p FilledPolygon) Draw(img draw.Image) image.Rectangle {
+	set := Setter(img)
 	// Create the outline.
 	subpolygon := NewPolygon(p.OutlineColor, p.Vertices...)
 
@@ -79,7 +80,7 @@ func (p FilledPolygon) Draw(img draw.Image) image.Rectangle {
 		for x := offsetX - 1; x <= subpolygonWidth+offsetX+1; x++ {
 			// Fill the polygon.
 			if insidePolygon {
-				img.Set(x, y, p.FillColor)
+				set(x, y, p.FillColor)
 			}
 			for _, line := range subpolygon.Lines {
 				// Skip lines we've already passed
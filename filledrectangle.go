@@ -28,10 +28,9 @@ func NewFilledRectangle(position image.Point, width, height int, outline, fill c
 
 // Draw draws the element to the img, img could be an image.RGBA* or screen buffer.
 func (r FilledRectangle) Draw(img draw.Image) image.Rectangle {
+	set := Setter(img)
 	for y := r.Position.Y; y < r.Position.Y+r.Height; y++ {
-		for x := r.Position.X; x < r.Position.X+r.Width; x++ {
-			img.Set(x, y, r.FillColor)
-		}
+		fillRow(img, set, r.Position.X, r.Position.X+r.Width, y, r.FillColor)
 	}
 
 	a := image.Point{r.Position.X, r.Position.Y}
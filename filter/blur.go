@@ -0,0 +1,65 @@
+package filter
+
+import (
+	"math"
+
+	"github.com/a-h/raster/sparse"
+)
+
+// BoxBlur averages each pixel with its neighbours within the radius.
+type BoxBlur struct {
+	Radius int
+}
+
+// Apply blurs the image.
+func (f BoxBlur) Apply(img *sparse.Image) *sparse.Image {
+	if f.Radius <= 0 || img.Len() == 0 {
+		return img
+	}
+	b := bufferFrom(img, img.DrawnBounds().Inset(-f.Radius))
+	b.convolve(boxKernel(f.Radius))
+	return b.toSparse(img.Bounds())
+}
+
+func boxKernel(radius int) []float64 {
+	size := radius*2 + 1
+	kernel := make([]float64, size)
+	for i := range kernel {
+		kernel[i] = 1 / float64(size)
+	}
+	return kernel
+}
+
+// GaussianBlur blurs the image using a Gaussian function, which gives a smoother result
+// than a BoxBlur. Sigma is the standard deviation of the function in pixels, the blur
+// spreads out to 3 times Sigma.
+type GaussianBlur struct {
+	Sigma float64
+}
+
+// Apply blurs the image.
+func (f GaussianBlur) Apply(img *sparse.Image) *sparse.Image {
+	if f.Sigma <= 0 || img.Len() == 0 {
+		return img
+	}
+	kernel := gaussianKernel(f.Sigma)
+	b := bufferFrom(img, img.DrawnBounds().Inset(-len(kernel)/2))
+	b.convolve(kernel)
+	return b.toSparse(img.Bounds())
+}
+
+func gaussianKernel(sigma float64) []float64 {
+	radius := int(math.Ceil(sigma * 3))
+	kernel := make([]float64, radius*2+1)
+	var total float64
+	for i := range kernel {
+		x := float64(i - radius)
+		kernel[i] = math.Exp(-(x * x) / (2 * sigma * sigma))
+		total += kernel[i]
+	}
+	// Normalise so that the kernel doesn't change the overall brightness.
+	for i := range kernel {
+		kernel[i] /= total
+	}
+	return kernel
+}
@@ -0,0 +1,89 @@
+package filter
+
+import (
+	"image/color"
+
+	"github.com/a-h/raster/sparse"
+)
+
+// ColorMatrix transforms the color of each pixel by multiplying the non-premultiplied
+// red, green, blue and alpha values (in the range 0 to 1) by a 4x5 matrix, where the
+// last column is an offset. e.g. the identity matrix is:
+//
+//	1, 0, 0, 0, 0,
+//	0, 1, 0, 0, 0,
+//	0, 0, 1, 0, 0,
+//	0, 0, 0, 1, 0,
+type ColorMatrix struct {
+	Matrix [20]float64
+}
+
+// IdentityColorMatrix defines a color matrix which has no effect.
+var IdentityColorMatrix = ColorMatrix{
+	Matrix: [20]float64{
+		1, 0, 0, 0, 0,
+		0, 1, 0, 0, 0,
+		0, 0, 1, 0, 0,
+		0, 0, 0, 1, 0,
+	},
+}
+
+// GrayscaleColorMatrix converts colors to shades of gray, based on their luminance.
+var GrayscaleColorMatrix = ColorMatrix{
+	Matrix: [20]float64{
+		0.2126, 0.7152, 0.0722, 0, 0,
+		0.2126, 0.7152, 0.0722, 0, 0,
+		0.2126, 0.7152, 0.0722, 0, 0,
+		0, 0, 0, 1, 0,
+	},
+}
+
+// SepiaColorMatrix gives colors a brown, old photograph tone.
+var SepiaColorMatrix = ColorMatrix{
+	Matrix: [20]float64{
+		0.393, 0.769, 0.189, 0, 0,
+		0.349, 0.686, 0.168, 0, 0,
+		0.272, 0.534, 0.131, 0, 0,
+		0, 0, 0, 1, 0,
+	},
+}
+
+// NewBrightnessColorMatrix creates a color matrix which adds the amount (-1 to 1) to each
+// of the red, green and blue values.
+func NewBrightnessColorMatrix(amount float64) ColorMatrix {
+	return ColorMatrix{
+		Matrix: [20]float64{
+			1, 0, 0, 0, amount,
+			0, 1, 0, 0, amount,
+			0, 0, 1, 0, amount,
+			0, 0, 0, 1, 0,
+		},
+	}
+}
+
+// Apply transforms the color of each pixel.
+func (f ColorMatrix) Apply(img *sparse.Image) *sparse.Image {
+	m := f.Matrix
+	output := sparse.NewImage(img.Bounds())
+	img.Each(func(x, y int, c color.RGBA) bool {
+		nc := color.NRGBA64Model.Convert(c).(color.NRGBA64)
+		r := float64(nc.R) / 0xffff
+		g := float64(nc.G) / 0xffff
+		b := float64(nc.B) / 0xffff
+		a := float64(nc.A) / 0xffff
+
+		r1 := m[0]*r + m[1]*g + m[2]*b + m[3]*a + m[4]
+		g1 := m[5]*r + m[6]*g + m[7]*b + m[8]*a + m[9]
+		b1 := m[10]*r + m[11]*g + m[12]*b + m[13]*a + m[14]
+		a1 := m[15]*r + m[16]*g + m[17]*b + m[18]*a + m[19]
+
+		output.Set(x, y, color.NRGBA{
+			R: toUint8(r1),
+			G: toUint8(g1),
+			B: toUint8(b1),
+			A: toUint8(a1),
+		})
+		return true
+	})
+	return output
+}
@@ -0,0 +1,118 @@
+// Package filter provides image filters, such as blurs and drop shadows, which can be applied to
+// the pixels of a raster.Composition before it's drawn.
+package filter
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/a-h/raster/sparse"
+)
+
+// Filter modifies the pixels of a sparse image, returning a new image. The input image is not
+// modified.
+type Filter interface {
+	Apply(img *sparse.Image) *sparse.Image
+}
+
+// Apply applies each of the filters in turn.
+func Apply(img *sparse.Image, filters ...Filter) *sparse.Image {
+	for _, f := range filters {
+		img = f.Apply(img)
+	}
+	return img
+}
+
+// buffer is a dense representation of an area of an image, holding premultiplied
+// red, green, blue and alpha values in the range 0 to 1.
+type buffer struct {
+	rect image.Rectangle
+	pix  []float64
+}
+
+func newBuffer(rect image.Rectangle) *buffer {
+	return &buffer{
+		rect: rect,
+		pix:  make([]float64, rect.Dx()*rect.Dy()*4),
+	}
+}
+
+// bufferFrom copies the drawn pixels of the sparse image which lie within the rectangle
+// into a buffer.
+func bufferFrom(img *sparse.Image, rect image.Rectangle) *buffer {
+	b := newBuffer(rect)
+	img.Each(func(x, y int, c color.RGBA) bool {
+		if !(image.Point{x, y}).In(rect) {
+			return true
+		}
+		i := b.offset(x, y)
+		b.pix[i+0] = float64(c.R) / 0xff
+		b.pix[i+1] = float64(c.G) / 0xff
+		b.pix[i+2] = float64(c.B) / 0xff
+		b.pix[i+3] = float64(c.A) / 0xff
+		return true
+	})
+	return b
+}
+
+func (b *buffer) offset(x, y int) int {
+	return ((y-b.rect.Min.Y)*b.rect.Dx() + (x - b.rect.Min.X)) * 4
+}
+
+// toSparse writes the pixels which aren't fully transparent to a new sparse image.
+func (b *buffer) toSparse(bounds image.Rectangle) *sparse.Image {
+	img := sparse.NewImage(bounds)
+	for y := b.rect.Min.Y; y < b.rect.Max.Y; y++ {
+		for x := b.rect.Min.X; x < b.rect.Max.X; x++ {
+			i := b.offset(x, y)
+			c := color.RGBA{
+				R: toUint8(b.pix[i+0]),
+				G: toUint8(b.pix[i+1]),
+				B: toUint8(b.pix[i+2]),
+				A: toUint8(b.pix[i+3]),
+			}
+			if c.A == 0 {
+				continue
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// convolve applies a one-dimensional kernel horizontally, then vertically. The kernel must
+// have an odd length, with its center in the middle.
+func (b *buffer) convolve(kernel []float64) {
+	radius := len(kernel) / 2
+	w, h := b.rect.Dx(), b.rect.Dy()
+	tmp := make([]float64, len(b.pix))
+
+	pass := func(src, dst []float64, length, lines int, index func(line, i int) int) {
+		for line := 0; line < lines; line++ {
+			for i := 0; i < length; i++ {
+				var r, g, bl, a float64
+				for k, weight := range kernel {
+					j := i + k - radius
+					if j < 0 || j >= length {
+						continue
+					}
+					si := index(line, j)
+					r += src[si+0] * weight
+					g += src[si+1] * weight
+					bl += src[si+2] * weight
+					a += src[si+3] * weight
+				}
+				di := index(line, i)
+				dst[di+0], dst[di+1], dst[di+2], dst[di+3] = r, g, bl, a
+			}
+		}
+	}
+
+	pass(b.pix, tmp, w, h, func(y, x int) int { return (y*w + x) * 4 })
+	pass(tmp, b.pix, h, w, func(x, y int) int { return (y*w + x) * 4 })
+}
+
+func toUint8(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(1, v)) * 0xff))
+}
@@ -0,0 +1,149 @@
+package filter
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/a-h/raster/sparse"
+
+	"golang.org/x/image/colornames"
+)
+
+func TestThatFiltersImplementTheFilterInterface(t *testing.T) {
+	filters := []interface{}{
+		BoxBlur{},
+		GaussianBlur{},
+		DropShadow{},
+		OuterGlow{},
+		ColorMatrix{},
+	}
+	for _, f := range filters {
+		if _, ok := f.(Filter); !ok {
+			t.Errorf("expected %T to implement Filter", f)
+		}
+	}
+}
+
+func TestGaussianKernelIsNormalised(t *testing.T) {
+	kernel := gaussianKernel(2)
+	if len(kernel) != 13 {
+		t.Errorf("expected a kernel of 13 values for a sigma of 2, got %d", len(kernel))
+	}
+	var total float64
+	for _, v := range kernel {
+		total += v
+	}
+	if math.Abs(total-1) > 0.0001 {
+		t.Errorf("expected the kernel to sum to 1, got %v", total)
+	}
+}
+
+func TestBlurs(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		spread int
+	}{
+		{
+			name:   "box",
+			filter: BoxBlur{Radius: 2},
+			spread: 2,
+		},
+		{
+			name:   "gaussian",
+			filter: GaussianBlur{Sigma: 1},
+			// The tail of the curve is too faint to be stored in 8 bits.
+			spread: 2,
+		},
+	}
+
+	for _, test := range tests {
+		img := sparse.NewImage(image.Rect(0, 0, 20, 20))
+		img.Set(10, 10, colornames.White)
+
+		blurred := test.filter.Apply(img)
+
+		if img.Len() != 1 {
+			t.Errorf("%s: expected the input image not to be modified", test.name)
+		}
+		expected := image.Rect(10-test.spread, 10-test.spread, 10+test.spread+1, 10+test.spread+1)
+		if actual := blurred.DrawnBounds(); !actual.Eq(expected) {
+			t.Errorf("%s: expected the pixel to be spread over %v, got %v", test.name, expected, actual)
+		}
+		center := blurred.At(10, 10).(color.RGBA)
+		if center.A == 0 || center.A == 0xff {
+			t.Errorf("%s: expected the center pixel to be partially transparent, got %v", test.name, center)
+		}
+		if center.R != center.A {
+			t.Errorf("%s: expected the center pixel to remain white (premultiplied), got %v", test.name, center)
+		}
+	}
+}
+
+func TestDropShadow(t *testing.T) {
+	img := sparse.NewImage(image.Rect(0, 0, 20, 20))
+	img.Set(5, 5, colornames.Red)
+
+	shadowed := DropShadow{Offset: image.Point{2, 3}, Color: colornames.Black}.Apply(img)
+
+	if shadowed.At(5, 5) != colornames.Red {
+		t.Errorf("expected the original pixel to be drawn over the shadow, got %v", shadowed.At(5, 5))
+	}
+	if shadowed.At(7, 8) != colornames.Black {
+		t.Errorf("expected the shadow at {7, 8}, got %v", shadowed.At(7, 8))
+	}
+	if shadowed.Len() != 2 {
+		t.Errorf("expected 2 pixels to be drawn, got %d", shadowed.Len())
+	}
+}
+
+func TestOuterGlow(t *testing.T) {
+	img := sparse.NewImage(image.Rect(0, 0, 20, 20))
+	img.Set(10, 10, colornames.White)
+
+	glowing := OuterGlow{Sigma: 1, Color: colornames.Yellow}.Apply(img)
+
+	if glowing.At(10, 10) != colornames.White {
+		t.Errorf("expected the original pixel to be drawn over the glow, got %v", glowing.At(10, 10))
+	}
+	around := glowing.At(11, 10).(color.RGBA)
+	if around.A == 0 || around.B != 0 {
+		t.Errorf("expected a yellow glow around the pixel, got %v", around)
+	}
+}
+
+func TestColorMatrix(t *testing.T) {
+	img := sparse.NewImage(image.Rect(0, 0, 20, 20))
+	img.Set(0, 0, color.RGBA{0xff, 0, 0, 0xff})
+
+	tests := []struct {
+		name     string
+		filter   ColorMatrix
+		expected color.RGBA
+	}{
+		{
+			name:     "identity",
+			filter:   IdentityColorMatrix,
+			expected: color.RGBA{0xff, 0, 0, 0xff},
+		},
+		{
+			name:     "grayscale",
+			filter:   GrayscaleColorMatrix,
+			expected: color.RGBA{0x36, 0x36, 0x36, 0xff},
+		},
+		{
+			name:     "brightness",
+			filter:   NewBrightnessColorMatrix(0.5),
+			expected: color.RGBA{0xff, 0x80, 0x80, 0xff},
+		},
+	}
+
+	for _, test := range tests {
+		actual := test.filter.Apply(img).At(0, 0)
+		if actual != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package filter
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/a-h/raster/sparse"
+)
+
+// DropShadow draws a blurred copy of the image's shape in the shadow color underneath the
+// image, shifted by the offset.
+type DropShadow struct {
+	Offset image.Point
+	// Sigma is the amount of blur applied to the shadow, see GaussianBlur.
+	Sigma float64
+	Color color.RGBA
+}
+
+// Apply adds the shadow to the image.
+func (f DropShadow) Apply(img *sparse.Image) *sparse.Image {
+	return shadow(img, f.Offset, f.Sigma, f.Color)
+}
+
+// OuterGlow draws a blurred copy of the image's shape in the glow color around the image.
+type OuterGlow struct {
+	// Sigma is the distance the glow spreads out, see GaussianBlur.
+	Sigma float64
+	Color color.RGBA
+}
+
+// Apply adds the glow to the image.
+func (f OuterGlow) Apply(img *sparse.Image) *sparse.Image {
+	return shadow(img, image.Point{}, f.Sigma, f.Color)
+}
+
+// shadow creates a silhouette of the image in the shadow color, blurs it, moves it by the
+// offset, and then draws the image over the top of it.
+func shadow(img *sparse.Image, offset image.Point, sigma float64, c color.RGBA) *sparse.Image {
+	if img.Len() == 0 {
+		return img
+	}
+
+	silhouette := sparse.NewImage(img.Bounds())
+	img.Each(func(x, y int, pc color.RGBA) bool {
+		silhouette.Set(x+offset.X, y+offset.Y, scaleAlpha(c, float64(pc.A)/0xff))
+		return true
+	})
+	if sigma > 0 {
+		silhouette = GaussianBlur{Sigma: sigma}.Apply(silhouette)
+	}
+
+	// Draw the original image over the top of the shadow.
+	b := bufferFrom(silhouette, silhouette.DrawnBounds().Union(img.DrawnBounds()))
+	img.Each(func(x, y int, pc color.RGBA) bool {
+		i := b.offset(x, y)
+		sa := float64(pc.A) / 0xff
+		b.pix[i+0] = float64(pc.R)/0xff + b.pix[i+0]*(1-sa)
+		b.pix[i+1] = float64(pc.G)/0xff + b.pix[i+1]*(1-sa)
+		b.pix[i+2] = float64(pc.B)/0xff + b.pix[i+2]*(1-sa)
+		b.pix[i+3] = sa + b.pix[i+3]*(1-sa)
+		return true
+	})
+	return b.toSparse(img.Bounds())
+}
+
+// scaleAlpha multiplies the color's alpha (and the premultiplied color values) by a.
+func scaleAlpha(c color.RGBA, a float64) color.RGBA {
+	return color.RGBA{
+		R: toUint8(float64(c.R) / 0xff * a),
+		G: toUint8(float64(c.G) / 0xff * a),
+		B: toUint8(float64(c.B) / 0xff * a),
+		A: toUint8(float64(c.A) / 0xff * a),
+	}
+}
@@ -0,0 +1,36 @@
+// Package composite combines colors, for the packages which draw semi-transparent pixels
+// over existing ones.
+package composite
+
+import "image/color"
+
+// Over returns the src color drawn over the dst color, using premultiplied alpha. Opaque
+// src colors, and the dst color under a transparent src color, are returned unchanged.
+func Over(dst, src color.Color) color.Color {
+	sr, sg, sb, sa := src.RGBA()
+	if sa == 0xffff {
+		return src
+	}
+	if sa == 0 {
+		return dst
+	}
+	dr, dg, db, da := dst.RGBA()
+	a := 0xffff - sa
+	return color.RGBA64{
+		R: uint16(sr + dr*a/0xffff),
+		G: uint16(sg + dg*a/0xffff),
+		B: uint16(sb + db*a/0xffff),
+		A: uint16(sa + da*a/0xffff),
+	}
+}
+
+// OverRGBA is Over for 8-bit colors, which avoids converting them to 16 bits.
+func OverRGBA(dst, src color.RGBA) color.RGBA {
+	a := 0xff - uint32(src.A)
+	return color.RGBA{
+		R: uint8(uint32(src.R) + uint32(dst.R)*a/0xff),
+		G: uint8(uint32(src.G) + uint32(dst.G)*a/0xff),
+		B: uint8(uint32(src.B) + uint32(dst.B)*a/0xff),
+		A: uint8(uint32(src.A) + uint32(dst.A)*a/0xff),
+	}
+}
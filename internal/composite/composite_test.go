@@ -0,0 +1,29 @@
+package composite
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestOver(t *testing.T) {
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	halfRed := color.RGBA{0x80, 0, 0, 0x80}
+	tests := []struct {
+		name     string
+		dst, src color.RGBA
+		expected color.RGBA
+	}{
+		{name: "opaque", dst: white, src: color.RGBA{0, 0, 0xff, 0xff}, expected: color.RGBA{0, 0, 0xff, 0xff}},
+		{name: "transparent", dst: white, src: color.RGBA{}, expected: white},
+		{name: "semi-transparent", dst: white, src: halfRed, expected: color.RGBA{0xff, 0x7f, 0x7f, 0xff}},
+		{name: "over nothing", dst: color.RGBA{}, src: halfRed, expected: halfRed},
+	}
+	for _, test := range tests {
+		if actual := color.RGBAModel.Convert(Over(test.dst, test.src)); actual != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, actual)
+		}
+		if actual := OverRGBA(test.dst, test.src); actual != test.expected {
+			t.Errorf("%s: expected the 8-bit version to return %v, got %v", test.name, test.expected, actual)
+		}
+	}
+}
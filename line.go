@@ -56,8 +56,9 @@ func (l *Line) ContainsPoint(p image.Point) bool {
 
 // Draw draws the element to the img, img could be an image.RGBA* or screen buffer.
 func (l *Line) Draw(img draw.Image) image.Rectangle {
+	set := Setter(img)
 	drawer := func(x, y int) bool {
-		img.Set(x, y, l.OutlineColor)
+		set(x, y, l.OutlineColor)
 		return true
 	}
 	line(l.From.X, l.From.Y, l.To.X, l.To.Y, drawer)
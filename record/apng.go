@@ -0,0 +1,157 @@
+package record
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"io"
+	"time"
+)
+
+// ErrFrameSize is returned when writing an APNG if the frames aren't all the same size.
+var ErrFrameSize = errors.New("record: all frames must be the same size")
+
+// WriteAPNG writes the frames as an animated PNG, which loops forever. Unlike a GIF, the
+// colors aren't reduced. Viewers which don't support animation show the first frame.
+// See https://wiki.mozilla.org/APNG_Specification
+func (r *Recorder) WriteAPNG(w io.Writer) error {
+	if len(r.Frames) == 0 {
+		return ErrNoFrames
+	}
+	size := r.Frames[0].Image.Bounds().Size()
+	for _, f := range r.Frames {
+		if f.Image.Bounds().Size() != size {
+			return ErrFrameSize
+		}
+	}
+
+	cw := &chunkWriter{w: w}
+	cw.signature()
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(size.X))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(size.Y))
+	ihdr[8] = 8  // Bit depth.
+	ihdr[9] = 6  // Color type: RGBA.
+	ihdr[10] = 0 // Compression method.
+	ihdr[11] = 0 // Filter method.
+	ihdr[12] = 0 // No interlacing.
+	cw.chunk("IHDR", ihdr)
+
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:], uint32(len(r.Frames)))
+	binary.BigEndian.PutUint32(actl[4:], 0) // Loop forever.
+	cw.chunk("acTL", actl)
+
+	// Every fcTL and fdAT chunk has a sequence number.
+	var sequence uint32
+	for i, f := range r.Frames {
+		cw.chunk("fcTL", frameControl(sequence, size, f.Delay))
+		sequence++
+
+		data, err := compress(f.Image)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			// The first frame is also the default image.
+			cw.chunk("IDAT", data)
+			continue
+		}
+		fdat := make([]byte, 4+len(data))
+		binary.BigEndian.PutUint32(fdat, sequence)
+		copy(fdat[4:], data)
+		cw.chunk("fdAT", fdat)
+		sequence++
+	}
+	cw.chunk("IEND", nil)
+	return cw.err
+}
+
+// frameControl creates the contents of a fcTL chunk for a frame which covers the whole
+// image.
+func frameControl(sequence uint32, size image.Point, delay time.Duration) []byte {
+	fctl := make([]byte, 26)
+	binary.BigEndian.PutUint32(fctl[0:], sequence)
+	binary.BigEndian.PutUint32(fctl[4:], uint32(size.X))
+	binary.BigEndian.PutUint32(fctl[8:], uint32(size.Y))
+	binary.BigEndian.PutUint32(fctl[12:], 0) // X offset.
+	binary.BigEndian.PutUint32(fctl[16:], 0) // Y offset.
+	ms := delay / time.Millisecond
+	if ms > 0xffff {
+		ms = 0xffff
+	}
+	binary.BigEndian.PutUint16(fctl[20:], uint16(ms))
+	binary.BigEndian.PutUint16(fctl[22:], 1000)
+	fctl[24] = 0 // Dispose: leave the frame in place.
+	fctl[25] = 0 // Blend: replace the previous frame.
+	return fctl
+}
+
+// compress converts the image to non-premultiplied RGBA scanlines, and compresses them.
+func compress(img *image.RGBA) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	b := img.Bounds()
+	row := make([]byte, 1+b.Dx()*4)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		// The first byte of each row is the filter type, 0 is no filter.
+		row[0] = 0
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			i := 1 + (x-b.Min.X)*4
+			row[i], row[i+1], row[i+2], row[i+3] = unpremultiply(c.R, c.A), unpremultiply(c.G, c.A), unpremultiply(c.B, c.A), c.A
+		}
+		if _, err := zw.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func unpremultiply(v, a uint8) uint8 {
+	if a == 0xff {
+		return v
+	}
+	if a == 0 {
+		return 0
+	}
+	return uint8(uint32(v) * 0xff / uint32(a))
+}
+
+// chunkWriter writes PNG chunks, keeping the first error.
+type chunkWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (cw *chunkWriter) write(b []byte) {
+	if cw.err != nil {
+		return
+	}
+	_, cw.err = cw.w.Write(b)
+}
+
+func (cw *chunkWriter) signature() {
+	cw.write([]byte("\x89PNG\r\n\x1a\n"))
+}
+
+func (cw *chunkWriter) chunk(name string, data []byte) {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(data)))
+	copy(header[4:], name)
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+	footer := make([]byte, 4)
+	binary.BigEndian.PutUint32(footer, crc.Sum32())
+	cw.write(header)
+	cw.write(data)
+	cw.write(footer)
+}
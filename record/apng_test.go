@@ -0,0 +1,103 @@
+package record
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+
+	"golang.org/x/image/colornames"
+)
+
+type chunk struct {
+	name string
+	data []byte
+}
+
+func readChunks(t *testing.T, b []byte) (chunks []chunk) {
+	if !bytes.HasPrefix(b, []byte("\x89PNG\r\n\x1a\n")) {
+		t.Fatalf("missing PNG signature")
+	}
+	b = b[8:]
+	for len(b) >= 12 {
+		n := binary.BigEndian.Uint32(b)
+		chunks = append(chunks, chunk{name: string(b[4:8]), data: b[8 : 8+n]})
+		b = b[12+n:]
+	}
+	return chunks
+}
+
+func TestWriteAPNG(t *testing.T) {
+	r := New(time.Millisecond * 40)
+	for _, c := range []color.RGBA{colornames.Red, colornames.Green, colornames.Blue} {
+		img := image.NewRGBA(image.Rect(0, 0, 8, 4))
+		img.SetRGBA(2, 2, c)
+		r.Add(img)
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteAPNG(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The first frame can be read by a normal PNG decoder.
+	first, err := png.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if r, g, b, a := first.At(2, 2).RGBA(); r != 0xffff || g != 0 || b != 0 || a != 0xffff {
+		t.Errorf("expected the first frame to be red, got %v", first.At(2, 2))
+	}
+
+	var names []string
+	var sequence []uint32
+	for _, c := range readChunks(t, buf.Bytes()) {
+		names = append(names, c.name)
+		switch c.name {
+		case "acTL":
+			if frames := binary.BigEndian.Uint32(c.data); frames != 3 {
+				t.Errorf("expected 3 frames, got %d", frames)
+			}
+		case "fcTL":
+			sequence = append(sequence, binary.BigEndian.Uint32(c.data))
+			if delay := binary.BigEndian.Uint16(c.data[20:]); delay != 40 {
+				t.Errorf("expected a delay of 40/1000, got %d", delay)
+			}
+		case "fdAT":
+			sequence = append(sequence, binary.BigEndian.Uint32(c.data))
+		}
+	}
+	expectedNames := []string{"IHDR", "acTL", "fcTL", "IDAT", "fcTL", "fdAT", "fcTL", "fdAT", "IEND"}
+	if len(names) != len(expectedNames) {
+		t.Fatalf("expected chunks %v, got %v", expectedNames, names)
+	}
+	for i := range names {
+		if names[i] != expectedNames[i] {
+			t.Errorf("expected chunks %v, got %v", expectedNames, names)
+			break
+		}
+	}
+	for i, s := range sequence {
+		if s != uint32(i) {
+			t.Errorf("expected sequence numbers to count up from 0, got %v", sequence)
+			break
+		}
+	}
+}
+
+func TestWriteAPNGErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := New(0).WriteAPNG(&buf); err != ErrNoFrames {
+		t.Errorf("expected ErrNoFrames, got %v", err)
+	}
+
+	r := New(0)
+	r.Add(image.NewRGBA(image.Rect(0, 0, 2, 2)))
+	r.Add(image.NewRGBA(image.Rect(0, 0, 3, 2)))
+	if err := r.WriteAPNG(&buf); err != ErrFrameSize {
+		t.Errorf("expected ErrFrameSize, got %v", err)
+	}
+}
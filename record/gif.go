@@ -0,0 +1,60 @@
+package record
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"math"
+	"time"
+)
+
+// GIFOptions controls how frames are converted to the 256 colors a GIF can hold.
+type GIFOptions struct {
+	// Palette is used for every frame. If it's nil, a palette is created from the colors
+	// of the frames.
+	Palette color.Palette
+	// Dither spreads the difference between the actual colors and the palette colors over
+	// nearby pixels, which gives smoother gradients.
+	Dither bool
+}
+
+// WriteGIF writes the frames as an animated GIF, which loops forever.
+func (r *Recorder) WriteGIF(w io.Writer, opts GIFOptions) error {
+	if len(r.Frames) == 0 {
+		return ErrNoFrames
+	}
+	p := opts.Palette
+	if p == nil {
+		images := make([]image.Image, len(r.Frames))
+		for i, f := range r.Frames {
+			images[i] = f.Image
+		}
+		p = Quantize(256, images...)
+	}
+	var drawer draw.Drawer = draw.Src
+	if opts.Dither {
+		drawer = draw.FloydSteinberg
+	}
+
+	anim := &gif.GIF{}
+	for _, f := range r.Frames {
+		b := f.Image.Bounds()
+		paletted := image.NewPaletted(b, p)
+		drawer.Draw(paletted, b, f.Image, b.Min)
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, gifDelay(f.Delay))
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// gifDelay converts the delay to 100ths of a second. Most viewers ignore delays of 0, so
+// the smallest delay is 1.
+func gifDelay(d time.Duration) int {
+	delay := int(math.Round(float64(d) / float64(time.Millisecond*10)))
+	if delay < 1 {
+		return 1
+	}
+	return delay
+}
@@ -0,0 +1,83 @@
+package record
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+	"time"
+
+	"golang.org/x/image/colornames"
+)
+
+func gradient(bounds image.Rectangle, offset int) *image.RGBA {
+	img := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			img.SetRGBA(x, y, color.RGBA{uint8(x * 4), uint8(y * 4), uint8(offset), 0xff})
+		}
+	}
+	return img
+}
+
+func TestWriteGIF(t *testing.T) {
+	r := New(time.Millisecond * 50)
+	for i := 0; i < 3; i++ {
+		r.Add(gradient(image.Rect(0, 0, 64, 64), i*100))
+	}
+
+	tests := []struct {
+		name string
+		opts GIFOptions
+	}{
+		{name: "adaptive palette"},
+		{name: "adaptive palette, dithered", opts: GIFOptions{Dither: true}},
+		{name: "fixed palette", opts: GIFOptions{Palette: color.Palette{colornames.Black, colornames.White}}},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := r.WriteGIF(&buf, test.opts); err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		anim, err := gif.DecodeAll(&buf)
+		if err != nil {
+			t.Fatalf("%s: failed to decode: %v", test.name, err)
+		}
+		if len(anim.Image) != 3 {
+			t.Errorf("%s: expected 3 frames, got %d", test.name, len(anim.Image))
+		}
+		for i, d := range anim.Delay {
+			if d != 5 {
+				t.Errorf("%s: frame %d: expected a delay of 5, got %d", test.name, i, d)
+			}
+		}
+		if test.opts.Palette != nil && len(anim.Image[0].Palette) != len(test.opts.Palette) {
+			t.Errorf("%s: expected the palette to be used, got %d colors", test.name, len(anim.Image[0].Palette))
+		}
+	}
+}
+
+func TestWriteGIFWithoutFrames(t *testing.T) {
+	var buf bytes.Buffer
+	if err := New(0).WriteGIF(&buf, GIFOptions{}); err != ErrNoFrames {
+		t.Errorf("expected ErrNoFrames, got %v", err)
+	}
+}
+
+func TestGIFDelay(t *testing.T) {
+	tests := []struct {
+		delay    time.Duration
+		expected int
+	}{
+		{delay: 0, expected: 1},
+		{delay: time.Millisecond * 5, expected: 1},
+		{delay: time.Millisecond * 20, expected: 2},
+		{delay: time.Second, expected: 100},
+	}
+	for _, test := range tests {
+		if actual := gifDelay(test.delay); actual != test.expected {
+			t.Errorf("%v: expected %d, got %d", test.delay, test.expected, actual)
+		}
+	}
+}
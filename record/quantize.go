@@ -0,0 +1,144 @@
+package record
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// Quantize creates a palette of up to n colors which represents the colors of the images,
+// using the median cut algorithm. Alpha is ignored, so the colors are fully opaque.
+func Quantize(n int, images ...image.Image) color.Palette {
+	// Count the colors, reduced to 5 bits per channel to keep the histogram small.
+	counts := make(map[uint16]int)
+	for _, img := range images {
+		bounds := img.Bounds()
+		rgba, isRGBA := img.(*image.RGBA)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				if isRGBA {
+					c := rgba.RGBAAt(x, y)
+					counts[uint16(c.R>>3)<<10|uint16(c.G>>3)<<5|uint16(c.B>>3)]++
+					continue
+				}
+				r, g, b, _ := img.At(x, y).RGBA()
+				counts[uint16(r>>11)<<10|uint16(g>>11)<<5|uint16(b>>11)]++
+			}
+		}
+	}
+	entries := make([]entry, 0, len(counts))
+	for k, count := range counts {
+		entries = append(entries, entry{
+			c:     [3]uint8{uint8(k >> 10 & 31), uint8(k >> 5 & 31), uint8(k & 31)},
+			count: count,
+		})
+	}
+	// Sort the entries, so that the palette doesn't depend on the order of the map.
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key() < entries[j].key()
+	})
+
+	boxes := []box{entries}
+	for len(boxes) < n {
+		// Split the box with the widest range of colors.
+		widest, widestRange := -1, 0
+		for i, b := range boxes {
+			if len(b) < 2 {
+				continue
+			}
+			if _, r := b.widestChannel(); r > widestRange {
+				widest, widestRange = i, r
+			}
+		}
+		if widest < 0 {
+			break
+		}
+		a, b := boxes[widest].split()
+		boxes[widest] = a
+		boxes = append(boxes, b)
+	}
+
+	p := make(color.Palette, 0, len(boxes))
+	for _, b := range boxes {
+		if len(b) > 0 {
+			p = append(p, b.average())
+		}
+	}
+	return p
+}
+
+// entry is a color, with 5 bits per channel, and the number of pixels which have it.
+type entry struct {
+	c     [3]uint8
+	count int
+}
+
+func (e entry) key() int {
+	return int(e.c[0])<<10 | int(e.c[1])<<5 | int(e.c[2])
+}
+
+// box is a group of colors which become a single palette color.
+type box []entry
+
+// widestChannel returns the channel with the largest difference between its smallest and
+// largest values, and the size of the difference.
+func (b box) widestChannel() (channel, size int) {
+	for ch := 0; ch < 3; ch++ {
+		min, max := uint8(31), uint8(0)
+		for _, e := range b {
+			if e.c[ch] < min {
+				min = e.c[ch]
+			}
+			if e.c[ch] > max {
+				max = e.c[ch]
+			}
+		}
+		if int(max-min) > size || ch == 0 {
+			channel, size = ch, int(max-min)
+		}
+	}
+	return channel, size
+}
+
+// split divides the box at the median pixel of its widest channel.
+func (b box) split() (box, box) {
+	ch, _ := b.widestChannel()
+	sort.SliceStable(b, func(i, j int) bool {
+		return b[i].c[ch] < b[j].c[ch]
+	})
+	total := 0
+	for _, e := range b {
+		total += e.count
+	}
+	seen := 0
+	for i, e := range b {
+		seen += e.count
+		if seen*2 >= total {
+			// Keep at least one entry in each half.
+			if i == len(b)-1 {
+				i--
+			}
+			return b[:i+1], b[i+1:]
+		}
+	}
+	return b[:len(b)/2], b[len(b)/2:]
+}
+
+// average returns the average color of the box, weighted by the number of pixels.
+func (b box) average() color.RGBA {
+	var sum [3]int
+	total := 0
+	for _, e := range b {
+		for ch := 0; ch < 3; ch++ {
+			sum[ch] += int(e.c[ch]) * e.count
+		}
+		total += e.count
+	}
+	var c [3]uint8
+	for ch := 0; ch < 3; ch++ {
+		// Scale the 5 bit value back up to 8 bits.
+		v := (sum[ch]*2 + total) / (total * 2)
+		c[ch] = uint8(v<<3 | v>>2)
+	}
+	return color.RGBA{c[0], c[1], c[2], 0xff}
+}
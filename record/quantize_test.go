@@ -0,0 +1,42 @@
+package record
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/colornames"
+)
+
+func TestQuantize(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			c := colornames.Red
+			if x >= 5 {
+				c = colornames.Blue
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+
+	p := Quantize(256, img)
+	if len(p) != 2 {
+		t.Fatalf("expected a color for each of the 2 colors, got %v", p)
+	}
+	for _, expected := range []color.RGBA{colornames.Red, colornames.Blue} {
+		if actual := p.Convert(expected); actual != expected {
+			t.Errorf("expected %v to be in the palette, got %v", expected, actual)
+		}
+	}
+}
+
+func TestQuantizeLimitsTheNumberOfColors(t *testing.T) {
+	img := gradient(image.Rect(0, 0, 64, 64), 0)
+	for _, n := range []int{1, 2, 16, 256} {
+		p := Quantize(n, img)
+		if len(p) != n {
+			t.Errorf("expected %d colors, got %d", n, len(p))
+		}
+	}
+}
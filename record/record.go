@@ -0,0 +1,71 @@
+// Package record captures animation frames, e.g. those drawn by a stage.Stage or a
+// world.World, and exports them as an animated GIF or APNG file.
+package record
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"time"
+
+	"github.com/a-h/raster/world"
+)
+
+// Frame is a captured image, and how long it's displayed for.
+type Frame struct {
+	Image *image.RGBA
+	Delay time.Duration
+}
+
+// Recorder captures frames.
+type Recorder struct {
+	// Delay is the time each frame is displayed for, e.g. the Tick of a world.World.
+	Delay  time.Duration
+	Frames []Frame
+	// next is the publisher which frames are passed on to, see Attach.
+	next world.Publisher
+}
+
+// New creates a Recorder which displays each frame for the delay.
+func New(delay time.Duration) *Recorder {
+	return &Recorder{
+		Delay: delay,
+	}
+}
+
+// ErrNoFrames is returned when writing a recording which doesn't contain any frames.
+var ErrNoFrames = errors.New("record: no frames have been recorded")
+
+// Add captures a copy of the img, e.g. after drawing a stage onto it.
+func (r *Recorder) Add(img image.Image) {
+	b := img.Bounds()
+	frame := image.NewRGBA(b)
+	draw.Draw(frame, b, img, b.Min, draw.Src)
+	r.Frames = append(r.Frames, Frame{Image: frame, Delay: r.Delay})
+}
+
+// Publish captures the img, and passes it on to the publisher the Recorder was attached
+// to, if any. It implements the world.Publisher interface.
+func (r *Recorder) Publish(img draw.Image) {
+	r.Add(img)
+	if r.next != nil {
+		r.next.Publish(img)
+	}
+}
+
+// Err returns the error of the publisher the Recorder was attached to, if it's a
+// world.ErrorPublisher, so that the world still stops when it fails.
+func (r *Recorder) Err() error {
+	if p, ok := r.next.(world.ErrorPublisher); ok {
+		return p.Err()
+	}
+	return nil
+}
+
+// Attach records the frames published by the world, using the world's Tick as the delay
+// between frames. Frames are still passed on to the world's existing Publisher.
+func (r *Recorder) Attach(w *world.World) {
+	r.Delay = w.Tick
+	r.next = w.Publisher
+	w.Publisher = r
+}
@@ -0,0 +1,89 @@
+package record
+
+import (
+	"image"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/a-h/raster/world"
+
+	"golang.org/x/image/colornames"
+)
+
+type countingPublisher struct {
+	count int
+}
+
+func (p *countingPublisher) Publish(img draw.Image) {
+	p.count++
+}
+
+func TestThatAddCapturesACopy(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	img.SetRGBA(1, 1, colornames.Red)
+
+	r := New(time.Millisecond * 20)
+	r.Add(img)
+	img.SetRGBA(1, 1, colornames.Blue)
+
+	if len(r.Frames) != 1 {
+		t.Fatalf("expected 1 frame, got %d", len(r.Frames))
+	}
+	if actual := r.Frames[0].Image.RGBAAt(1, 1); actual != colornames.Red {
+		t.Errorf("expected the frame not to change when the image does, got %v", actual)
+	}
+	if r.Frames[0].Delay != time.Millisecond*20 {
+		t.Errorf("expected a delay of 20ms, got %v", r.Frames[0].Delay)
+	}
+}
+
+func TestAttach(t *testing.T) {
+	next := &countingPublisher{}
+	w := &world.World{
+		Publisher: next,
+		Tick:      time.Millisecond * 40,
+	}
+	r := New(0)
+	r.Attach(w)
+
+	w.Publisher.Publish(image.NewRGBA(image.Rect(0, 0, 10, 10)))
+
+	if len(r.Frames) != 1 {
+		t.Errorf("expected the frame to be recorded, got %d frames", len(r.Frames))
+	}
+	if r.Frames[0].Delay != w.Tick {
+		t.Errorf("expected the world's tick to be used as the delay, got %v", r.Frames[0].Delay)
+	}
+	if next.count != 1 {
+		t.Errorf("expected the frame to be passed on to the existing publisher, got %d", next.count)
+	}
+}
+
+func TestThatErrorsArePassedOnFromTheAttachedPublisher(t *testing.T) {
+	r := New(0)
+	r.Attach(&world.World{Publisher: &countingPublisher{}})
+	r.Publish(image.NewRGBA(image.Rect(0, 0, 10, 10)))
+	if err := r.Err(); err != nil {
+		t.Errorf("expected no error from a publisher which can't fail, got %v", err)
+	}
+
+	// The PNGPublisher can't create a directory inside a file.
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	w := &world.World{Publisher: world.NewPNGPublisher(filepath.Join(file, "frames"))}
+	r = New(0)
+	r.Attach(w)
+	w.Publisher.Publish(image.NewRGBA(image.Rect(0, 0, 10, 10)))
+	p, ok := w.Publisher.(world.ErrorPublisher)
+	if !ok {
+		t.Fatalf("expected the Recorder to be an ErrorPublisher")
+	}
+	if p.Err() == nil {
+		t.Errorf("expected the error of the PNGPublisher to be returned")
+	}
+}
@@ -0,0 +1,209 @@
+package raster
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"runtime"
+	"sync"
+)
+
+// Renderer draws Composables onto an image using multiple goroutines. Each Composition is
+// drawn onto a recording by one of a pool of workers. The image is then split into tiles,
+// and the workers play back the recordings onto the tiles, in the order the Compositions
+// were provided, blending them with the pixels underneath.
+//
+// Other Composables may read the pixels of the image they're drawn onto, so they're drawn
+// directly onto the image, in order, between the Compositions which come before and after
+// them. The result is the same as drawing the Composables one after another.
+type Renderer struct {
+	// TileSize is the width and height of each tile.
+	TileSize int
+	// Workers is the number of goroutines used to draw.
+	Workers int
+}
+
+// NewRenderer creates a Renderer which uses 64x64 pixel tiles, and a worker for each CPU.
+func NewRenderer() *Renderer {
+	return &Renderer{
+		TileSize: 64,
+		Workers:  runtime.GOMAXPROCS(0),
+	}
+}
+
+// subImager is implemented by the image types of the standard library, e.g. *image.RGBA.
+// The sub images share pixels with the original image, so each tile can be drawn directly.
+type subImager interface {
+	SubImage(r image.Rectangle) image.Image
+}
+
+// Draw draws the components onto the img, in order, returning the area drawn. Images which
+// can't be split into sub images which share their pixels (e.g. *sparse.Image) can't be
+// written to concurrently, so they're drawn on the calling goroutine, as they are when
+// there's only one worker.
+func (r *Renderer) Draw(img draw.Image, components ...Composable) (area image.Rectangle) {
+	if _, ok := img.(subImager); !ok || r.Workers < 2 {
+		for _, c := range components {
+			area = area.Union(c.Draw(img))
+		}
+		return area
+	}
+	grid := newTileGrid(img.Bounds(), r.TileSize)
+
+	var compositions []*Composition
+	for _, c := range components {
+		if composition, ok := c.(*Composition); ok {
+			compositions = append(compositions, composition)
+			continue
+		}
+		area = area.Union(r.drawCompositions(img, grid, compositions))
+		compositions = compositions[:0]
+		area = area.Union(c.Draw(img))
+	}
+	return area.Union(r.drawCompositions(img, grid, compositions))
+}
+
+// drawCompositions draws the compositions onto the img in parallel, in order, returning the
+// area drawn.
+func (r *Renderer) drawCompositions(img draw.Image, grid tileGrid, compositions []*Composition) (area image.Rectangle) {
+	if len(compositions) == 0 {
+		return area
+	}
+	// Compositions cache their pixels, so build the caches first, to avoid building them
+	// from multiple goroutines at the same time.
+	for _, c := range compositions {
+		c.prepare()
+	}
+
+	recordings := make([]*recording, len(compositions))
+	r.parallel(len(compositions), func(i int) {
+		recordings[i] = record(compositions[i], grid)
+	})
+	for _, rec := range recordings {
+		area = area.Union(rec.area)
+	}
+
+	r.parallel(len(grid.tiles), func(i int) {
+		if tile, ok := img.(subImager).SubImage(grid.tiles[i]).(draw.Image); ok {
+			play(tile, recordings, i)
+		}
+	})
+	return area
+}
+
+// parallel calls f for each number from 0 to n-1, using the pool of workers.
+func (r *Renderer) parallel(n int, f func(i int)) {
+	workers := r.Workers
+	if workers > n {
+		workers = n
+	}
+	if workers < 2 {
+		for i := 0; i < n; i++ {
+			f(i)
+		}
+		return
+	}
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				f(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+}
+
+// tileGrid splits an area into square tiles, from the top left.
+type tileGrid struct {
+	bounds  image.Rectangle
+	size    int
+	columns int
+	tiles   []image.Rectangle
+}
+
+func newTileGrid(bounds image.Rectangle, size int) tileGrid {
+	if size <= 0 {
+		size = biggestSide(bounds)
+	}
+	g := tileGrid{bounds: bounds, size: size}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += size {
+		g.columns = 0
+		for x := bounds.Min.X; x < bounds.Max.X; x += size {
+			g.tiles = append(g.tiles, image.Rect(x, y, x+size, y+size).Intersect(bounds))
+			g.columns++
+		}
+	}
+	return g
+}
+
+func biggestSide(r image.Rectangle) int {
+	if r.Dx() > r.Dy() {
+		return r.Dx()
+	}
+	return r.Dy()
+}
+
+// index returns the index of the tile which contains the point, or -1 if it's outside of
+// the grid.
+func (g tileGrid) index(x, y int) int {
+	if !(image.Point{x, y}).In(g.bounds) {
+		return -1
+	}
+	return ((y-g.bounds.Min.Y)/g.size)*g.columns + (x-g.bounds.Min.X)/g.size
+}
+
+// pixel is a recorded call to Set.
+type pixel struct {
+	x, y int32
+	c    color.RGBA
+}
+
+// recording holds the pixels set by a Composition, split by tile, in the order they were
+// set.
+type recording struct {
+	tiles [][]pixel
+	area  image.Rectangle
+	// Compositions which blend are blended with the pixels underneath when played back.
+	blend     bool
+	blendMode BlendMode
+	opacity   float64
+}
+
+// record draws the Composition, recording the pixels it sets.
+func record(c *Composition, grid tileGrid) *recording {
+	rec := &recording{
+		tiles:     make([][]pixel, len(grid.tiles)),
+		blend:     c.blends(),
+		blendMode: c.BlendMode,
+		opacity:   c.opacity(),
+	}
+	rec.area = c.each(func(x, y int, pixelColor color.RGBA) {
+		if i := grid.index(x, y); i >= 0 {
+			rec.tiles[i] = append(rec.tiles[i], pixel{int32(x), int32(y), pixelColor})
+		}
+	})
+	return rec
+}
+
+// play draws the pixels of each recording which are within the tile onto the img.
+func play(img draw.Image, recordings []*recording, tile int) {
+	set := Setter(img)
+	for _, rec := range recordings {
+		for _, p := range rec.tiles[tile] {
+			x, y := int(p.x), int(p.y)
+			if rec.blend {
+				img.Set(x, y, rec.blendMode.Blend(img.At(x, y), p.c, rec.opacity))
+				continue
+			}
+			set(x, y, p.c)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package raster
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/a-h/raster/affine"
+	"github.com/a-h/raster/filter"
+	"github.com/a-h/raster/sparse"
+
+	"golang.org/x/image/colornames"
+)
+
+// tint is a Composable which reads the pixels underneath it, by drawing a semi-transparent
+// color over them.
+type tint struct {
+	area image.Rectangle
+	c    color.RGBA
+}
+
+func (t tint) Draw(img draw.Image) image.Rectangle {
+	draw.Draw(img, t.area, image.NewUniform(t.c), image.Point{}, draw.Over)
+	return t.area.Intersect(img.Bounds())
+}
+
+func (t tint) Bounds() image.Rectangle {
+	return t.area
+}
+
+func scene() []Composable {
+	blended := NewComposition(image.Point{40, 40},
+		NewFilledCircle(image.Point{30, 30}, 30, colornames.Yellow, colornames.Yellow))
+	blended.BlendMode = BlendMultiply
+	blended.Transparency = 0.25
+
+	// Scaling up maps several pixels onto the same point, so the order they're blended in
+	// matters.
+	rotated := NewComposition(image.Point{100, 20},
+		NewFilledRectangle(image.Point{0, 0}, 40, 20, colornames.Blue, colornames.Lightblue))
+	rotated.Transformation = affine.NewRotationAboutTransformation(30, image.Point{20, 10}).
+		Combine(affine.NewScaleTransformation(1.5, 1.5))
+	rotated.Transparency = 0.5
+
+	shadowed := NewComposition(image.Point{10, 120}, NewSquare(image.Point{0, 0}, 30, colornames.Green))
+	shadowed.Filters = []filter.Filter{filter.DropShadow{Offset: image.Point{3, 3}, Sigma: 2, Color: colornames.Black}}
+
+	return []Composable{
+		NewFilledRectangle(image.Point{-10, -10}, 300, 300, colornames.White, colornames.White),
+		NewFilledCircle(image.Point{60, 60}, 50, colornames.Red, colornames.Pink),
+		blended,
+		tint{area: image.Rect(30, 30, 130, 90), c: color.RGBA{0, 0x40, 0, 0x40}},
+		NewLine(image.Point{0, 199}, image.Point{199, 0}, colornames.Black),
+		rotated,
+		// The same composition can be drawn more than once.
+		rotated,
+		NewFilledPolygon(colornames.Purple, colornames.Orchid, image.Point{150, 150}, image.Point{190, 160}, image.Point{160, 195}),
+		NewText(image.Point{20, 170}, "Hello!", colornames.White),
+		shadowed,
+	}
+}
+
+func drawSerially(img draw.Image, components []Composable) (area image.Rectangle) {
+	for _, c := range components {
+		area = area.Union(c.Draw(img))
+	}
+	return area
+}
+
+func TestThatTheRendererMatchesSerialDrawing(t *testing.T) {
+	bounds := image.Rect(0, 0, 200, 200)
+	expected := image.NewRGBA(bounds)
+	expectedArea := drawSerially(expected, scene())
+
+	renderers := map[string]*Renderer{
+		"default":       NewRenderer(),
+		"single worker": {TileSize: 64, Workers: 1},
+		"uneven tiles":  {TileSize: 7, Workers: 4},
+		"more workers":  {TileSize: 16, Workers: 32},
+		"single tile":   {TileSize: 0, Workers: 4},
+		"large tiles":   {TileSize: 500, Workers: 4},
+	}
+	for name, r := range renderers {
+		actual := image.NewRGBA(bounds)
+		area := r.Draw(actual, scene()...)
+		if area != expectedArea {
+			t.Errorf("%s: expected area %v, got %v", name, expectedArea, area)
+		}
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				if actual.RGBAAt(x, y) != expected.RGBAAt(x, y) {
+					t.Fatalf("%s: at (%d, %d) expected %v, got %v", name, x, y, expected.RGBAAt(x, y), actual.RGBAAt(x, y))
+				}
+			}
+		}
+	}
+}
+
+func TestThatTheRendererDrawsOntoImagesWhichCantBeSplit(t *testing.T) {
+	bounds := image.Rect(0, 0, 200, 200)
+	expected := sparse.NewImage(bounds)
+	drawSerially(expected, scene())
+
+	actual := sparse.NewImage(bounds)
+	NewRenderer().Draw(actual, scene()...)
+
+	if actual.Len() != expected.Len() {
+		t.Fatalf("expected %d pixels, got %d", expected.Len(), actual.Len())
+	}
+	expected.Each(func(x, y int, c color.RGBA) bool {
+		if a, _ := actual.Lookup(x, y); a != c {
+			t.Fatalf("at (%d, %d) expected %v, got %v", x, y, c, a)
+		}
+		return true
+	})
+}
+
+func TestThatTheRendererIsDeterministic(t *testing.T) {
+	bounds := image.Rect(0, 0, 200, 200)
+	r := &Renderer{TileSize: 8, Workers: 8}
+	first := image.NewRGBA(bounds)
+	r.Draw(first, scene()...)
+	for i := 0; i < 5; i++ {
+		next := image.NewRGBA(bounds)
+		r.Draw(next, scene()...)
+		for j := range first.Pix {
+			if first.Pix[j] != next.Pix[j] {
+				t.Fatalf("attempt %d: output differs at byte %d", i, j)
+			}
+		}
+	}
+}
+
+// benchmarkScene returns large Compositions, which the Renderer draws in parallel.
+func benchmarkScene() []Composable {
+	return []Composable{
+		NewComposition(image.Point{}, NewFilledCircle(image.Point{500, 500}, 500, colornames.White, colornames.Aliceblue)),
+		NewComposition(image.Point{50, 50}, NewFilledCircle(image.Point{200, 200}, 200, colornames.Red, colornames.Pink)),
+		NewComposition(image.Point{550, 550}, NewFilledCircle(image.Point{200, 200}, 200, colornames.Green, colornames.Lightgreen)),
+	}
+}
+
+func BenchmarkRendererSerial(b *testing.B) {
+	img := image.NewRGBA(image.Rect(0, 0, 1000, 1000))
+	components := benchmarkScene()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		drawSerially(img, components)
+	}
+}
+
+func BenchmarkRendererParallel(b *testing.B) {
+	img := image.NewRGBA(image.Rect(0, 0, 1000, 1000))
+	components := benchmarkScene()
+	r := NewRenderer()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Draw(img, components...)
+	}
+}
@@ -0,0 +1,57 @@
+package raster
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/a-h/raster/sparse"
+)
+
+// Setter returns a function which sets pixels on the img. The common image types are
+// written to directly, to avoid the cost of the draw.Image interface and of converting
+// the color.
+func Setter(img draw.Image) func(x, y int, c color.RGBA) {
+	switch dst := img.(type) {
+	case *image.RGBA:
+		return func(x, y int, c color.RGBA) {
+			if !(image.Point{x, y}.In(dst.Rect)) {
+				return
+			}
+			i := dst.PixOffset(x, y)
+			s := dst.Pix[i : i+4 : i+4]
+			s[0], s[1], s[2], s[3] = c.R, c.G, c.B, c.A
+		}
+	case *sparse.Image:
+		return dst.SetRGBA
+	}
+	return func(x, y int, c color.RGBA) {
+		img.Set(x, y, c)
+	}
+}
+
+// fillRow sets the pixels from x0 up to, but not including, x1 on row y to the color c.
+func fillRow(img draw.Image, set func(x, y int, c color.RGBA), x0, x1, y int, c color.RGBA) {
+	if dst, ok := img.(*image.RGBA); ok {
+		if y < dst.Rect.Min.Y || y >= dst.Rect.Max.Y {
+			return
+		}
+		if x0 < dst.Rect.Min.X {
+			x0 = dst.Rect.Min.X
+		}
+		if x1 > dst.Rect.Max.X {
+			x1 = dst.Rect.Max.X
+		}
+		if x0 >= x1 {
+			return
+		}
+		row := dst.Pix[dst.PixOffset(x0, y):dst.PixOffset(x1, y)]
+		for i := 0; i < len(row); i += 4 {
+			row[i], row[i+1], row[i+2], row[i+3] = c.R, c.G, c.B, c.A
+		}
+		return
+	}
+	for x := x0; x < x1; x++ {
+		set(x, y, c)
+	}
+}
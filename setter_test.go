@@ -0,0 +1,109 @@
+package raster
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/a-h/raster/sparse"
+
+	"golang.org/x/image/colornames"
+)
+
+// opaqueImage hides the type of the image, so that the fast paths aren't used.
+type opaqueImage struct {
+	draw.Image
+}
+
+func TestThatFastPathsMatchTheDrawImageInterface(t *testing.T) {
+	shapes := []Composable{
+		NewLine(image.Point{-5, -5}, image.Point{120, 60}, colornames.Red),
+		NewCircle(image.Point{50, 50}, 60, colornames.Green),
+		NewFilledCircle(image.Point{90, 10}, 30, colornames.Blue, colornames.Yellow),
+		NewFilledRectangle(image.Point{-10, 80}, 200, 40, colornames.Black, colornames.Orange),
+		NewFilledPolygon(colornames.Purple, colornames.Pink, image.Point{10, 10}, image.Point{40, 10}, image.Point{25, 40}),
+	}
+	tests := []struct {
+		name     string
+		newImage func(r image.Rectangle) draw.Image
+	}{
+		{
+			name:     "*image.RGBA",
+			newImage: func(r image.Rectangle) draw.Image { return image.NewRGBA(r) },
+		},
+		{
+			name:     "*sparse.Image",
+			newImage: func(r image.Rectangle) draw.Image { return sparse.NewImage(r) },
+		},
+	}
+
+	bounds := image.Rect(0, 0, 100, 100)
+	for _, test := range tests {
+		fast, slow := test.newImage(bounds), test.newImage(bounds)
+		for _, s := range shapes {
+			s.Draw(fast)
+			s.Draw(opaqueImage{slow})
+		}
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				expected := color.RGBAModel.Convert(slow.At(x, y))
+				if actual := color.RGBAModel.Convert(fast.At(x, y)); actual != expected {
+					t.Fatalf("%s: at (%d, %d) expected %v, got %v", test.name, x, y, expected, actual)
+				}
+			}
+		}
+	}
+}
+
+func TestFillRow(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	set := Setter(img)
+
+	fillRow(img, set, -5, 3, 2, colornames.Red)
+	fillRow(img, set, 8, 20, 2, colornames.Blue)
+	fillRow(img, set, 0, 10, -1, colornames.Green)
+	fillRow(img, set, 5, 5, 3, colornames.Green)
+
+	for x := 0; x < 10; x++ {
+		expected := color.RGBA{}
+		if x < 3 {
+			expected = colornames.Red
+		}
+		if x >= 8 {
+			expected = colornames.Blue
+		}
+		if actual := img.RGBAAt(x, 2); actual != expected {
+			t.Errorf("at x=%d, expected %v, got %v", x, expected, actual)
+		}
+		if actual := img.RGBAAt(x, 3); actual != (color.RGBA{}) {
+			t.Errorf("empty row: at x=%d, expected nothing to be drawn, got %v", x, actual)
+		}
+	}
+}
+
+func BenchmarkFilledRectangleRGBA(b *testing.B) {
+	img := image.NewRGBA(image.Rect(0, 0, 1000, 1000))
+	r := NewFilledRectangle(image.Point{0, 0}, 1000, 1000, colornames.White, colornames.Aliceblue)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Draw(img)
+	}
+}
+
+func BenchmarkFilledRectangleDrawImage(b *testing.B) {
+	img := opaqueImage{image.NewRGBA(image.Rect(0, 0, 1000, 1000))}
+	r := NewFilledRectangle(image.Point{0, 0}, 1000, 1000, colornames.White, colornames.Aliceblue)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Draw(img)
+	}
+}
+
+func BenchmarkFilledCircleDrawImage(b *testing.B) {
+	img := opaqueImage{image.NewRGBA(image.Rect(0, 0, 1000, 1000))}
+	for i := 0; i < b.N; i++ {
+		p := NewFilledCircle(image.Point{500, 500}, 500, colornames.White, colornames.Aliceblue)
+		p.Draw(img)
+	}
+}
@@ -3,25 +3,72 @@ package sparse
 import (
 	"image"
 	"image/color"
+	"math"
+	"math/bits"
+	"sort"
 )
 
-// Image holds the written pixels in a map instead of creating memory to hold all pixels.
+// Tiles are 32 pixels square, so that each 64 bit word of a tile's drawn mask holds two
+// rows of pixels.
+const (
+	tileShift = 5
+	tileSize  = 1 << tileShift
+	tileMask  = tileSize - 1
+	tileArea  = tileSize * tileSize
+)
+
+// tile holds a square of pixels, and a bit for each pixel which records whether it has
+// been drawn. The pixels are stored in the image's color model, packed into the 4 bytes
+// of a color.RGBA, see encode.
+type tile struct {
+	pix   [tileArea]color.RGBA
+	drawn [tileArea / 64]uint64
+	count int
+}
+
+// Image holds the written pixels in tiles, which are only created when a pixel within them
+// is written, instead of creating memory to hold all pixels.
 type Image struct {
 	bounds image.Rectangle
-	Drawn  map[image.Point]color.Color
+	model  color.Model
+	kind   modelKind
+	tiles  map[image.Point]*tile
+	// keys holds the keys of the tiles in scanline order. Keys are only appended in place,
+	// other changes replace the slice, so that iterating over the keys is unaffected by
+	// drawing.
+	keys  []image.Point
+	count int
+	// spare holds tiles which have been cleared, ready to be reused.
+	spare []*tile
+	// drawnBounds is kept up to date as pixels are drawn, but deleting pixels from the
+	// edge leaves it stale until it's next requested.
+	drawnBounds      image.Rectangle
+	drawnBoundsStale bool
+	// The most recently written tile, since drawing tends to write nearby pixels. It's
+	// only updated by writes, so that the image can be read from several goroutines.
+	lastKey  image.Point
+	lastTile *tile
 }
 
-// NewImage creates a sparse image.
+// NewImage creates a sparse image which uses the color.RGBAModel.
 func NewImage(bounds image.Rectangle) *Image {
+	return NewImageWithModel(bounds, color.RGBAModel)
+}
+
+// NewImageWithModel creates a sparse image which converts colors to the color model when
+// they're set, e.g. color.GrayModel, color.NRGBAModel or a color.Palette.
+func NewImageWithModel(bounds image.Rectangle, model color.Model) *Image {
 	return &Image{
 		bounds: bounds,
-		Drawn:  make(map[image.Point]color.Color),
+		model:  model,
+		kind:   kindOf(model),
+		tiles:  make(map[image.Point]*tile),
 	}
 }
 
 // ColorModel returns the Image's color model.
 func (img *Image) ColorModel() color.Model {
-	return color.RGBAModel
+	return img.model
 }
 
 // Bounds returns the domain for which At can return non-zero color.
@@ -30,43 +77,343 @@ func (img *Image) Bounds() image.Rectangle {
 	return img.bounds
 }
 
-// DrawnBounds returns the bounds of the image which contain information, not
-// the theoretical size of the image.
+// DrawnBounds returns the smallest rectangle which contains all of the drawn pixels,
+// rather than the theoretical size of the image. Like image.Rectangle, the maximum
+// coordinates are exclusive, so a single pixel at (x, y) has DrawnBounds of
+// image.Rect(x, y, x+1, y+1). An empty rectangle is returned if nothing has been drawn.
 func (img *Image) DrawnBounds() image.Rectangle {
-	if len(img.Drawn) == 0 {
-		return image.Rect(0, 0, 0, 0)
+	if img.drawnBoundsStale {
+		img.recalculateDrawnBounds()
 	}
+	return img.drawnBounds
+}
 
-	b := img.Bounds()
-	minX, minY := b.Dx(), b.Dy()
-	maxX, maxY := 0, 0
-	for p := range img.Drawn {
-		if p.X > maxX {
-			maxX = p.X
-		}
-		if p.Y > maxY {
-			maxY = p.Y
-		}
-		if p.X < minX {
-			minX = p.X
+// recalculateDrawnBounds visits every pixel to find the drawn bounds, which is only
+// required after pixels at the edge have been deleted.
+func (img *Image) recalculateDrawnBounds() {
+	img.drawnBounds = image.Rectangle{}
+	img.drawnBoundsStale = false
+	first := true
+	img.Each(func(x, y int, c color.RGBA) bool {
+		if first {
+			img.drawnBounds = image.Rect(x, y, x+1, y+1)
+			first = false
+			return true
 		}
-		if p.Y < minY {
-			minY = p.Y
+		img.extendDrawnBounds(x, y)
+		return true
+	})
+}
+
+func (img *Image) extendDrawnBounds(x, y int) {
+	b := &img.drawnBounds
+	if x < b.Min.X {
+		b.Min.X = x
+	}
+	if y < b.Min.Y {
+		b.Min.Y = y
+	}
+	if x >= b.Max.X {
+		b.Max.X = x + 1
+	}
+	if y >= b.Max.Y {
+		b.Max.Y = y + 1
+	}
+}
+
+// Len returns the number of pixels which have been drawn.
+func (img *Image) Len() int {
+	return img.count
+}
+
+// tileKey returns the coordinates of the tile which contains the pixel, and the index of
+// the pixel within the tile.
+func tileKey(x, y int) (key image.Point, i int) {
+	// Shifting rounds down, even for negative numbers.
+	return image.Point{x >> tileShift, y >> tileShift}, ((y & tileMask) << tileShift) | (x & tileMask)
+}
+
+// tile returns the tile with the key, or nil if it doesn't exist.
+func (img *Image) tile(key image.Point) *tile {
+	if img.lastTile != nil && img.lastKey == key {
+		return img.lastTile
+	}
+	return img.tiles[key]
+}
+
+// writableTile returns the tile with the key, creating it if it doesn't exist, and makes it
+// the most recently written tile.
+func (img *Image) writableTile(key image.Point) *tile {
+	if img.lastTile != nil && img.lastKey == key {
+		return img.lastTile
+	}
+	t, ok := img.tiles[key]
+	if !ok {
+		t = img.newTile()
+		img.tiles[key] = t
+		if n := len(img.keys); n == 0 || keyLess(img.keys[n-1], key) {
+			// Drawing tends to be in scanline order, so most tiles are added at the end.
+			img.keys = append(img.keys, key)
+		} else {
+			i := img.keyIndex(key)
+			keys := make([]image.Point, len(img.keys)+1)
+			copy(keys, img.keys[:i])
+			keys[i] = key
+			copy(keys[i+1:], img.keys[i:])
+			img.keys = keys
 		}
 	}
-	return image.Rect(minX, minY, maxX, maxY)
+	img.lastKey, img.lastTile = key, t
+	return t
+}
+
+// newTile returns a spare tile, or a new one if there aren't any.
+func (img *Image) newTile() *tile {
+	if n := len(img.spare); n > 0 {
+		t := img.spare[n-1]
+		img.spare = img.spare[:n-1]
+		return t
+	}
+	return new(tile)
 }
 
-// Set the pixel with x, y coordinates to the color c.
+// Set the pixel with x, y coordinates to the color c, converted to the image's color model.
 func (img *Image) Set(x, y int, c color.Color) {
-	img.Drawn[image.Point{x, y}] = c
+	img.setRaw(x, y, img.encode(c))
+}
+
+// SetRGBA sets the pixel with x, y coordinates to the color c. When the image uses the
+// color.RGBAModel, this avoids the cost of converting the color.
+func (img *Image) SetRGBA(x, y int, c color.RGBA) {
+	if img.kind != rgbaKind {
+		c = img.encode(c)
+	}
+	img.setRaw(x, y, c)
+}
+
+// setRaw sets the pixel to a color which has already been encoded.
+func (img *Image) setRaw(x, y int, c color.RGBA) {
+	key, i := tileKey(x, y)
+	t := img.writableTile(key)
+	t.pix[i] = c
+	if t.drawn[i>>6]&(1<<uint(i&63)) == 0 {
+		t.drawn[i>>6] |= 1 << uint(i&63)
+		t.count++
+		img.count++
+		if img.count == 1 {
+			img.drawnBounds = image.Rect(x, y, x+1, y+1)
+			img.drawnBoundsStale = false
+		} else if !img.drawnBoundsStale {
+			img.extendDrawnBounds(x, y)
+		}
+	}
+}
+
+// Drawn returns a map of the drawn pixels, in the image's color model, which replaces the
+// Drawn field that the pixels used to be stored in.
+//
+// Deprecated: the pixels are no longer stored in a map, so every pixel is copied. Use Each,
+// Lookup and Len instead.
+func (img *Image) Drawn() map[image.Point]color.Color {
+	drawn := make(map[image.Point]color.Color, img.count)
+	img.eachRaw(func(x, y int, c color.RGBA) bool {
+		drawn[image.Point{x, y}] = img.decode(c)
+		return true
+	})
+	return drawn
+}
+
+// Lookup returns the color of the pixel at (x, y) as a color.RGBA, regardless of the
+// image's color model, and whether it has been drawn.
+func (img *Image) Lookup(x, y int) (c color.RGBA, ok bool) {
+	raw, ok := img.lookupRaw(x, y)
+	if !ok {
+		return c, false
+	}
+	return img.toRGBA(raw), true
+}
+
+func (img *Image) lookupRaw(x, y int) (c color.RGBA, ok bool) {
+	key, i := tileKey(x, y)
+	t := img.tile(key)
+	if t == nil || t.drawn[i>>6]&(1<<uint(i&63)) == 0 {
+		return c, false
+	}
+	return t.pix[i], true
 }
 
-// At returns the color of the pixel at (x, y).
+// At returns the color of the pixel at (x, y), in the image's color model.
 func (img *Image) At(x, y int) color.Color {
-	p := image.Point{x, y}
-	if c, ok := img.Drawn[p]; ok {
-		return c
+	raw, ok := img.lookupRaw(x, y)
+	if !ok {
+		if img.kind == rgbaKind {
+			return color.RGBA{}
+		}
+		return img.model.Convert(color.Transparent)
+	}
+	return img.decode(raw)
+}
+
+// Delete removes the pixel at (x, y), so that it's no longer drawn.
+func (img *Image) Delete(x, y int) {
+	key, i := tileKey(x, y)
+	t := img.tile(key)
+	if t == nil || t.drawn[i>>6]&(1<<uint(i&63)) == 0 {
+		return
+	}
+	t.drawn[i>>6] &^= 1 << uint(i&63)
+	t.pix[i] = color.RGBA{}
+	t.count--
+	img.count--
+	if t.count == 0 {
+		delete(img.tiles, key)
+		i := img.keyIndex(key)
+		img.keys = append(img.keys[:i:i], img.keys[i+1:]...)
+		img.lastTile = nil
+	}
+	b := img.drawnBounds
+	if x == b.Min.X || y == b.Min.Y || x == b.Max.X-1 || y == b.Max.Y-1 {
+		img.drawnBoundsStale = true
+	}
+}
+
+// Clear removes all of the drawn pixels. The memory used by the tiles is kept, so that
+// drawing the next frame doesn't need to allocate any.
+func (img *Image) Clear() {
+	img.clearTiles()
+	// The keys are replaced, rather than truncated, so that anything still iterating over
+	// them isn't affected by drawing.
+	img.keys = nil
+}
+
+// clearTiles removes all of the drawn pixels, moving the tiles to the spare pool. The keys
+// are left for the caller to reset.
+func (img *Image) clearTiles() {
+	for key, t := range img.tiles {
+		*t = tile{}
+		img.spare = append(img.spare, t)
+		delete(img.tiles, key)
+	}
+	img.count = 0
+	img.lastTile = nil
+	img.drawnBounds = image.Rectangle{}
+	img.drawnBoundsStale = false
+}
+
+// Each calls f for each drawn pixel, until f returns false. The pixels are visited in
+// scanline order, top to bottom, then left to right. The colors are provided as
+// color.RGBA, regardless of the image's color model.
+func (img *Image) Each(f func(x, y int, c color.RGBA) bool) {
+	if img.kind == rgbaKind {
+		img.eachRaw(f)
+		return
+	}
+	img.eachRaw(func(x, y int, c color.RGBA) bool {
+		return f(x, y, img.toRGBA(c))
+	})
+}
+
+// eachRaw calls f for each drawn pixel, with the colors as they're stored.
+func (img *Image) eachRaw(f func(x, y int, c color.RGBA) bool) {
+	keys := img.keys
+	for start := 0; start < len(keys); {
+		// Find the tiles which are on the same row.
+		end := start
+		for end < len(keys) && keys[end].Y == keys[start].Y {
+			end++
+		}
+		for ly := 0; ly < tileSize; ly++ {
+			for _, key := range keys[start:end] {
+				t := img.tiles[key]
+				if t == nil {
+					// The tile was deleted by f.
+					continue
+				}
+				if !t.eachInRow(key, ly, f) {
+					return
+				}
+			}
+		}
+		start = end
 	}
-	return color.RGBA{}
+}
+
+// EachInRow calls f for each drawn pixel in the row, from left to right, until f returns
+// false.
+func (img *Image) EachInRow(y int, f func(x int, c color.RGBA) bool) {
+	ty, ly := y>>tileShift, y&tileMask
+	rowFunc := func(x, y int, c color.RGBA) bool {
+		return f(x, img.toRGBA(c))
+	}
+	keys := img.keys
+	for _, key := range keys[img.keyIndex(image.Point{math.MinInt, ty}):] {
+		if key.Y > ty {
+			return
+		}
+		t := img.tiles[key]
+		if t == nil {
+			continue
+		}
+		if !t.eachInRow(key, ly, rowFunc) {
+			return
+		}
+	}
+}
+
+// Span is a horizontal run of drawn pixels.
+type Span struct {
+	// Y is the row of the span.
+	Y int
+	// X is the position of the first pixel.
+	X int
+	// Pix holds the colors of the pixels, starting at X.
+	Pix []color.RGBA
+}
+
+// EachSpan calls f for each horizontal run of drawn pixels, in scanline order, until f
+// returns false. The Pix slice is reused between calls, so it must be copied if it's
+// needed after f returns.
+func (img *Image) EachSpan(f func(s Span) bool) {
+	var s Span
+	stopped := false
+	img.Each(func(x, y int, c color.RGBA) bool {
+		if len(s.Pix) > 0 && (y != s.Y || x != s.X+len(s.Pix)) {
+			if !f(s) {
+				stopped = true
+				return false
+			}
+			s.Pix = s.Pix[:0]
+		}
+		if len(s.Pix) == 0 {
+			s.X, s.Y = x, y
+		}
+		s.Pix = append(s.Pix, c)
+		return true
+	})
+	if !stopped && len(s.Pix) > 0 {
+		f(s)
+	}
+}
+
+// eachInRow calls f for each drawn pixel in the row ly of the tile, from left to right.
+func (t *tile) eachInRow(key image.Point, ly int, f func(x, y int, c color.RGBA) bool) bool {
+	// Each 64 bit word of the drawn mask holds two rows of 32 pixels.
+	set := (t.drawn[ly>>1] >> uint((ly&1)*tileSize)) & (1<<tileSize - 1)
+	y := key.Y<<tileShift | ly
+	for set != 0 {
+		lx := bits.TrailingZeros64(set)
+		set &^= 1 << uint(lx)
+		if !f(key.X<<tileShift|lx, y, t.pix[ly<<tileShift|lx]) {
+			return false
+		}
+	}
+	return true
+}
+
+// keyIndex returns the index of the key in the sorted keys, or the index where it would be
+// inserted if it's not present.
+func (img *Image) keyIndex(key image.Point) int {
+	return sort.Search(len(img.keys), func(i int) bool {
+		return !keyLess(img.keys[i], key)
+	})
 }
@@ -4,6 +4,8 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
+	"reflect"
+	"sync"
 	"testing"
 
 	"golang.org/x/image/colornames"
@@ -44,18 +46,102 @@ func TestDrawnBounds(t *testing.T) {
 	}
 
 	img.Set(49, 50, colornames.White)
-	expected = image.Rect(49, 50, 49, 50)
-	if !img.DrawnBounds().Eq(expected) {
+	expected = image.Rect(49, 50, 50, 51)
+	if img.DrawnBounds() != expected {
 		t.Errorf("After we've written a single pixel  expected DrawnBounds of %v, but got %v.", expected, img.DrawnBounds())
 	}
 
 	img.Set(59, 60, colornames.White)
-	expected = image.Rect(49, 50, 59, 60)
-	if !img.DrawnBounds().Eq(expected) {
+	expected = image.Rect(49, 50, 60, 61)
+	if img.DrawnBounds() != expected {
 		t.Errorf("After we've written two points  expected DrawnBounds of %v, but got %v.", expected, img.DrawnBounds())
 	}
 }
 
+func TestDrawnBoundsWithNegativeAndOffsetCoordinates(t *testing.T) {
+	tests := []struct {
+		name     string
+		bounds   image.Rectangle
+		points   []image.Point
+		expected image.Rectangle
+	}{
+		{
+			name:     "negative coordinates",
+			bounds:   image.Rect(-100, -100, 100, 100),
+			points:   []image.Point{{-50, -40}, {-10, -20}},
+			expected: image.Rect(-50, -40, -9, -19),
+		},
+		{
+			name:     "spanning the origin",
+			bounds:   image.Rect(-100, -100, 100, 100),
+			points:   []image.Point{{-1, 5}, {3, -7}},
+			expected: image.Rect(-1, -7, 4, 6),
+		},
+		{
+			name:     "bounds which don't start at the origin",
+			bounds:   image.Rect(200, 300, 400, 500),
+			points:   []image.Point{{350, 450}, {390, 310}},
+			expected: image.Rect(350, 310, 391, 451),
+		},
+		{
+			name:     "pixels at the origin",
+			bounds:   image.Rect(0, 0, 10, 10),
+			points:   []image.Point{{0, 0}},
+			expected: image.Rect(0, 0, 1, 1),
+		},
+	}
+
+	for _, test := range tests {
+		img := NewImage(test.bounds)
+		for _, p := range test.points {
+			img.Set(p.X, p.Y, colornames.White)
+		}
+		if actual := img.DrawnBounds(); actual != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestDrawnBoundsAfterDeletion(t *testing.T) {
+	img := NewImage(image.Rect(0, 0, 100, 100))
+	img.Set(10, 10, colornames.White)
+	img.Set(20, 20, colornames.White)
+	img.Set(30, 30, colornames.White)
+
+	img.Delete(20, 20)
+	if expected := image.Rect(10, 10, 31, 31); img.DrawnBounds() != expected {
+		t.Errorf("after deleting a pixel in the middle, expected %v, got %v", expected, img.DrawnBounds())
+	}
+
+	img.Delete(30, 30)
+	if expected := image.Rect(10, 10, 11, 11); img.DrawnBounds() != expected {
+		t.Errorf("after deleting a pixel at the edge, expected %v, got %v", expected, img.DrawnBounds())
+	}
+
+	img.Set(5, 50, colornames.White)
+	if expected := image.Rect(5, 10, 11, 51); img.DrawnBounds() != expected {
+		t.Errorf("after drawing again, expected %v, got %v", expected, img.DrawnBounds())
+	}
+
+	img.Clear()
+	if img.DrawnBounds() != (image.Rectangle{}) {
+		t.Errorf("after clearing, expected empty bounds, got %v", img.DrawnBounds())
+	}
+}
+
+func BenchmarkDrawnBounds(b *testing.B) {
+	img := NewImage(image.Rect(0, 0, 500, 500))
+	for y := 0; y < 500; y++ {
+		for x := 0; x < 500; x++ {
+			img.Set(x, y, colornames.Red)
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		img.DrawnBounds()
+	}
+}
+
 func TestSetAndAtFunctions(t *testing.T) {
 	img := NewImage(image.Rect(0, 0, 100, 100))
 
@@ -71,3 +157,284 @@ func TestSetAndAtFunctions(t *testing.T) {
 		t.Errorf("Unset pixels should just return an empty struct, but got %v", unwritten)
 	}
 }
+
+func TestNegativeCoordinates(t *testing.T) {
+	img := NewImage(image.Rect(-100, -100, 100, 100))
+
+	points := []image.Point{{-1, -1}, {-33, 0}, {0, -64}, {-100, -100}, {99, 99}}
+	for _, p := range points {
+		img.Set(p.X, p.Y, colornames.Red)
+	}
+	for _, p := range points {
+		if img.At(p.X, p.Y) != colornames.Red {
+			t.Errorf("%v: expected red, got %v", p, img.At(p.X, p.Y))
+		}
+	}
+	if img.At(1, 1) != (color.RGBA{}) {
+		t.Errorf("{1, 1}: expected the mirror of {-1, -1} to be unset, got %v", img.At(1, 1))
+	}
+	if img.Len() != len(points) {
+		t.Errorf("expected %d pixels, got %d", len(points), img.Len())
+	}
+}
+
+func TestLookupAndDelete(t *testing.T) {
+	img := NewImage(image.Rect(0, 0, 100, 100))
+
+	// A transparent pixel is still drawn.
+	img.Set(10, 10, color.RGBA{})
+	if _, ok := img.Lookup(10, 10); !ok {
+		t.Error("expected a transparent pixel to be recorded as drawn")
+	}
+	if _, ok := img.Lookup(11, 10); ok {
+		t.Error("expected an unwritten pixel not to be drawn")
+	}
+
+	// Writing the same pixel twice only counts it once.
+	img.Set(20, 20, colornames.Red)
+	img.Set(20, 20, colornames.Blue)
+	if img.Len() != 2 {
+		t.Errorf("expected 2 pixels, got %d", img.Len())
+	}
+	if c, _ := img.Lookup(20, 20); c != colornames.Blue {
+		t.Errorf("expected the second write to win, got %v", c)
+	}
+
+	img.Delete(20, 20)
+	if _, ok := img.Lookup(20, 20); ok {
+		t.Error("expected the pixel to be deleted")
+	}
+	if img.Len() != 1 {
+		t.Errorf("after deleting, expected 1 pixel, got %d", img.Len())
+	}
+
+	img.Clear()
+	if img.Len() != 0 {
+		t.Errorf("after clearing, expected no pixels, got %d", img.Len())
+	}
+}
+
+func TestThatColorsAreConvertedToTheColorModel(t *testing.T) {
+	img := NewImage(image.Rect(0, 0, 100, 100))
+	img.Set(0, 0, color.NRGBA{0xff, 0, 0, 0x80})
+
+	expected := color.RGBA{0x80, 0, 0, 0x80}
+	if img.At(0, 0) != expected {
+		t.Errorf("expected %v, got %v", expected, img.At(0, 0))
+	}
+}
+
+func TestEachVisitsEveryPixelInTheSameOrder(t *testing.T) {
+	img := NewImage(image.Rect(0, 0, 100, 100))
+	for i := 0; i < 500; i++ {
+		x, y := (i*37)%100-50, (i*91)%100-50
+		img.Set(x, y, color.RGBA{uint8(i), 0, 0, 0xff})
+	}
+
+	visit := func() (points []image.Point) {
+		img.Each(func(x, y int, c color.RGBA) bool {
+			if actual, _ := img.Lookup(x, y); actual != c {
+				t.Errorf("{%d, %d}: expected %v, got %v", x, y, actual, c)
+			}
+			points = append(points, image.Point{x, y})
+			return true
+		})
+		return
+	}
+
+	first := visit()
+	if len(first) != img.Len() {
+		t.Errorf("expected %d pixels to be visited, got %d", img.Len(), len(first))
+	}
+	for i := 0; i < 10; i++ {
+		if next := visit(); !reflect.DeepEqual(first, next) {
+			t.Fatal("expected pixels to be visited in the same order each time")
+		}
+	}
+
+	visited := 0
+	img.Each(func(x, y int, c color.RGBA) bool {
+		visited++
+		return visited < 3
+	})
+	if visited != 3 {
+		t.Errorf("expected iteration to stop after 3 pixels, got %d", visited)
+	}
+}
+
+func TestEachVisitsPixelsInScanlineOrder(t *testing.T) {
+	img := NewImage(image.Rect(-100, -100, 100, 100))
+	expected := []image.Point{
+		{-70, -90}, {40, -90},
+		{-1, -1}, {0, -1}, {31, -1}, {32, -1}, {99, -1},
+		{-100, 0}, {0, 0},
+		{5, 33},
+	}
+	// Write the pixels in reverse.
+	for i := len(expected) - 1; i >= 0; i-- {
+		img.Set(expected[i].X, expected[i].Y, colornames.White)
+	}
+
+	var actual []image.Point
+	img.Each(func(x, y int, c color.RGBA) bool {
+		actual = append(actual, image.Point{x, y})
+		return true
+	})
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestThatDeletedTilesAreNotVisited(t *testing.T) {
+	img := NewImage(image.Rect(-100, -100, 100, 100))
+	img.Set(-50, 0, colornames.White)
+	img.Set(0, 0, colornames.White)
+	img.Set(50, 0, colornames.White)
+	img.Delete(0, 0)
+	img.Set(50, 50, colornames.White)
+
+	var actual []image.Point
+	img.Each(func(x, y int, c color.RGBA) bool {
+		actual = append(actual, image.Point{x, y})
+		// Drawing while iterating doesn't change the tiles which are visited.
+		img.Set(x, y-60, colornames.White)
+		return true
+	})
+	expected := []image.Point{{-50, 0}, {50, 0}, {50, 50}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestEachInRow(t *testing.T) {
+	img := NewImage(image.Rect(-100, -100, 100, 100))
+	img.Set(50, -1, colornames.White)
+	img.Set(-50, -1, colornames.White)
+	img.Set(0, -1, colornames.White)
+	img.Set(0, 0, colornames.White)
+	img.Set(0, -2, colornames.White)
+
+	var actual []int
+	img.EachInRow(-1, func(x int, c color.RGBA) bool {
+		actual = append(actual, x)
+		return true
+	})
+	expected := []int{-50, 0, 50}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestEachSpan(t *testing.T) {
+	img := NewImage(image.Rect(0, 0, 100, 100))
+	// A run which crosses a tile boundary.
+	for x := 30; x < 35; x++ {
+		img.Set(x, 1, color.RGBA{uint8(x), 0, 0, 0xff})
+	}
+	img.Set(40, 1, colornames.White)
+	img.Set(0, 2, colornames.White)
+	img.Set(1, 2, colornames.White)
+
+	type span struct {
+		X, Y, Len int
+		First     color.RGBA
+	}
+	var actual []span
+	img.EachSpan(func(s Span) bool {
+		actual = append(actual, span{X: s.X, Y: s.Y, Len: len(s.Pix), First: s.Pix[0]})
+		return true
+	})
+	expected := []span{
+		{X: 30, Y: 1, Len: 5, First: color.RGBA{30, 0, 0, 0xff}},
+		{X: 40, Y: 1, Len: 1, First: colornames.White},
+		{X: 0, Y: 2, Len: 2, First: colornames.White},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+// mapImage is the previous implementation of the sparse image, used as a baseline for
+// the benchmarks.
+type mapImage map[image.Point]color.Color
+
+func BenchmarkSetFilledArea(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		img := NewImage(image.Rect(0, 0, 500, 500))
+		for y := 0; y < 500; y++ {
+			for x := 0; x < 500; x++ {
+				img.Set(x, y, colornames.Red)
+			}
+		}
+	}
+}
+
+func BenchmarkMapSetFilledArea(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		img := make(mapImage)
+		for y := 0; y < 500; y++ {
+			for x := 0; x < 500; x++ {
+				img[image.Point{x, y}] = colornames.Red
+			}
+		}
+	}
+}
+
+func BenchmarkEachFilledArea(b *testing.B) {
+	img := NewImage(image.Rect(0, 0, 500, 500))
+	for y := 0; y < 500; y++ {
+		for x := 0; x < 500; x++ {
+			img.Set(x, y, colornames.Red)
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		img.Each(func(x, y int, c color.RGBA) bool {
+			return true
+		})
+	}
+}
+
+func BenchmarkMapRangeFilledArea(b *testing.B) {
+	img := make(mapImage)
+	for y := 0; y < 500; y++ {
+		for x := 0; x < 500; x++ {
+			img[image.Point{x, y}] = colornames.Red
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for range img {
+		}
+	}
+}
+
+func TestDrawn(t *testing.T) {
+	img := NewImageWithModel(image.Rect(0, 0, 10, 10), color.GrayModel)
+	img.Set(1, 2, colornames.White)
+	expected := map[image.Point]color.Color{{1, 2}: color.Gray{0xff}}
+	if actual := img.Drawn(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestConcurrentReads(t *testing.T) {
+	img := NewImage(image.Rect(0, 0, 100, 100))
+	for x := 0; x < 100; x += 10 {
+		img.Set(x, x, color.RGBA{R: 0xff, A: 0xff})
+	}
+
+	// Run with -race to check that reading doesn't write to the image.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for x := 0; x < 100; x++ {
+				img.At(x, x)
+				img.Lookup(x, 99-x)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,84 @@
+package sparse
+
+import "image/color"
+
+// modelKind identifies the color models which can be stored without losing information.
+type modelKind int
+
+const (
+	// rgbaKind stores the color.RGBA as-is.
+	rgbaKind modelKind = iota
+	// nrgbaKind stores the color.NRGBA values in the R, G, B and A bytes.
+	nrgbaKind
+	// grayKind stores the color.Gray value in the R byte.
+	grayKind
+	// palettedKind stores the index of the color in the palette in the R byte.
+	palettedKind
+	// otherKind converts colors to the color model, then stores them as color.RGBA.
+	otherKind
+)
+
+func kindOf(model color.Model) modelKind {
+	switch m := model.(type) {
+	case color.Palette:
+		if len(m) > 0 && len(m) <= 256 {
+			return palettedKind
+		}
+		return otherKind
+	}
+	switch model {
+	case color.RGBAModel:
+		return rgbaKind
+	case color.NRGBAModel:
+		return nrgbaKind
+	case color.GrayModel:
+		return grayKind
+	}
+	return otherKind
+}
+
+// encode converts the color to the image's color model, and packs it into 4 bytes.
+func (img *Image) encode(c color.Color) color.RGBA {
+	switch img.kind {
+	case nrgbaKind:
+		n := color.NRGBAModel.Convert(c).(color.NRGBA)
+		return color.RGBA{n.R, n.G, n.B, n.A}
+	case grayKind:
+		g := color.GrayModel.Convert(c).(color.Gray)
+		return color.RGBA{R: g.Y}
+	case palettedKind:
+		return color.RGBA{R: uint8(img.model.(color.Palette).Index(c))}
+	case otherKind:
+		c = img.model.Convert(c)
+	}
+	if rgba, ok := c.(color.RGBA); ok {
+		return rgba
+	}
+	return color.RGBAModel.Convert(c).(color.RGBA)
+}
+
+// decode unpacks the 4 bytes into a color in the image's color model.
+func (img *Image) decode(raw color.RGBA) color.Color {
+	switch img.kind {
+	case nrgbaKind:
+		return color.NRGBA{raw.R, raw.G, raw.B, raw.A}
+	case grayKind:
+		return color.Gray{Y: raw.R}
+	case palettedKind:
+		return img.model.(color.Palette)[raw.R]
+	case otherKind:
+		return img.model.Convert(raw)
+	}
+	return raw
+}
+
+// toRGBA unpacks the 4 bytes into a color.RGBA.
+func (img *Image) toRGBA(raw color.RGBA) color.RGBA {
+	switch img.kind {
+	case rgbaKind, otherKind:
+		return raw
+	case grayKind:
+		return color.RGBA{raw.R, raw.R, raw.R, 0xff}
+	}
+	return color.RGBAModel.Convert(img.decode(raw)).(color.RGBA)
+}
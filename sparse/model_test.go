@@ -0,0 +1,120 @@
+package sparse
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestColorModels(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.Black, color.White}
+
+	tests := []struct {
+		name       string
+		model      color.Model
+		set        color.Color
+		expectedAt color.Color
+		expected   color.RGBA
+	}{
+		{
+			name:       "RGBA",
+			model:      color.RGBAModel,
+			set:        color.NRGBA{0xff, 0, 0, 0x80},
+			expectedAt: color.RGBA{0x80, 0, 0, 0x80},
+			expected:   color.RGBA{0x80, 0, 0, 0x80},
+		},
+		{
+			name:       "NRGBA keeps the unpremultiplied color",
+			model:      color.NRGBAModel,
+			set:        color.NRGBA{0xff, 0, 0, 0x80},
+			expectedAt: color.NRGBA{0xff, 0, 0, 0x80},
+			expected:   color.RGBA{0x80, 0, 0, 0x80},
+		},
+		{
+			name:       "Gray",
+			model:      color.GrayModel,
+			set:        color.RGBA{0xff, 0xff, 0xff, 0xff},
+			expectedAt: color.Gray{0xff},
+			expected:   color.RGBA{0xff, 0xff, 0xff, 0xff},
+		},
+		{
+			name:       "Paletted uses the closest color",
+			model:      palette,
+			set:        color.RGBA{0xf0, 0xf0, 0xf0, 0xff},
+			expectedAt: color.White,
+			expected:   color.RGBA{0xff, 0xff, 0xff, 0xff},
+		},
+		{
+			name:       "Other models are converted",
+			model:      color.Gray16Model,
+			set:        color.RGBA{0xff, 0xff, 0xff, 0xff},
+			expectedAt: color.Gray16{0xffff},
+			expected:   color.RGBA{0xff, 0xff, 0xff, 0xff},
+		},
+	}
+
+	for _, test := range tests {
+		img := NewImageWithModel(image.Rect(0, 0, 10, 10), test.model)
+		img.Set(1, 1, test.set)
+
+		if actual := img.At(1, 1); actual != test.expectedAt {
+			t.Errorf("%s: At: expected %v, got %v", test.name, test.expectedAt, actual)
+		}
+		if actual, _ := img.Lookup(1, 1); actual != test.expected {
+			t.Errorf("%s: Lookup: expected %v, got %v", test.name, test.expected, actual)
+		}
+		img.Each(func(x, y int, c color.RGBA) bool {
+			if c != test.expected {
+				t.Errorf("%s: Each: expected %v, got %v", test.name, test.expected, c)
+			}
+			return true
+		})
+
+		img.SetRGBA(2, 2, test.expected)
+		if actual := img.At(2, 2); actual != test.expectedAt {
+			t.Errorf("%s: SetRGBA: expected %v, got %v", test.name, test.expectedAt, actual)
+		}
+	}
+}
+
+func TestColorModelIsKeptByOperations(t *testing.T) {
+	img := NewImageWithModel(image.Rect(0, 0, 10, 10), color.GrayModel)
+	img.Set(1, 1, color.Gray{0x80})
+
+	images := map[string]*Image{
+		"Clone":     img.Clone(),
+		"Crop":      img.Crop(image.Rect(0, 0, 5, 5)),
+		"Translate": img.Translate(image.Pt(1, 1)),
+		"Diff":      Diff(NewImage(img.Bounds()), img).Added,
+	}
+	for name, actual := range images {
+		if actual.ColorModel() != color.GrayModel {
+			t.Errorf("%s: expected the GrayModel, got %v", name, actual.ColorModel())
+		}
+	}
+}
+
+func TestThatDiffComparesColorsAcrossModels(t *testing.T) {
+	from := NewImageWithModel(image.Rect(0, 0, 10, 10), color.GrayModel)
+	from.Set(1, 1, color.Gray{0x80})
+	to := NewImageWithModel(image.Rect(0, 0, 10, 10), color.NRGBAModel)
+	to.Set(1, 1, color.NRGBA{0x80, 0x80, 0x80, 0xff})
+
+	d := Diff(from, to)
+	if d.Changed.Len() != 0 {
+		t.Errorf("expected the same color in a different model to be unchanged, got %d changes", d.Changed.Len())
+	}
+}
+
+func TestFromImageUsesTheColorModelOfTheSource(t *testing.T) {
+	src := image.NewGray(image.Rect(0, 0, 2, 2))
+	src.SetGray(1, 1, color.Gray{0x80})
+
+	img := FromImage(src)
+	if img.ColorModel() != color.GrayModel {
+		t.Errorf("expected the GrayModel, got %v", img.ColorModel())
+	}
+	if img.At(1, 1) != (color.Gray{0x80}) {
+		t.Errorf("expected %v, got %v", color.Gray{0x80}, img.At(1, 1))
+	}
+}
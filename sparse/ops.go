@@ -0,0 +1,209 @@
+package sparse
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math/bits"
+
+	"github.com/a-h/raster/internal/composite"
+)
+
+// FromImage creates a sparse image from the pixels of the src image which aren't fully
+// transparent. The sparse image uses the same color model as the src image.
+func FromImage(src image.Image) *Image {
+	b := src.Bounds()
+	img := NewImageWithModel(b, src.ColorModel())
+	if rgba, ok := src.(*image.RGBA); ok {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if c := rgba.RGBAAt(x, y); c.A != 0 {
+					img.SetRGBA(x, y, c)
+				}
+			}
+		}
+		return img
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := src.At(x, y)
+			if _, _, _, a := c.RGBA(); a != 0 {
+				img.Set(x, y, c)
+			}
+		}
+	}
+	return img
+}
+
+// RGBA creates a dense image the size of the sparse image's bounds, containing the drawn
+// pixels. Pixels outside of the bounds are not included.
+func (img *Image) RGBA() *image.RGBA {
+	dst := image.NewRGBA(img.bounds)
+	img.EachSpan(func(s Span) bool {
+		for i, c := range s.Pix {
+			if (image.Point{s.X + i, s.Y}).In(img.bounds) {
+				dst.SetRGBA(s.X+i, s.Y, c)
+			}
+		}
+		return true
+	})
+	return dst
+}
+
+// Clone returns a copy of the image.
+func (img *Image) Clone() *Image {
+	c := NewImageWithModel(img.bounds, img.model)
+	for k, t := range img.tiles {
+		copied := *t
+		c.tiles[k] = &copied
+	}
+	c.keys = append([]image.Point(nil), img.keys...)
+	c.count = img.count
+	c.drawnBounds = img.drawnBounds
+	c.drawnBoundsStale = img.drawnBoundsStale
+	return c
+}
+
+// Merge draws the pixels of src onto the image using the compositing operator. With
+// draw.Src, the pixels of src replace the existing pixels. With draw.Over, they're drawn
+// over the top of the existing pixels, so that semi-transparent pixels are blended.
+func (img *Image) Merge(src *Image, op draw.Op) {
+	src.Each(func(x, y int, c color.RGBA) bool {
+		if op == draw.Over && c.A != 0xff {
+			if existing, ok := img.Lookup(x, y); ok {
+				c = composite.OverRGBA(existing, c)
+			}
+		}
+		img.SetRGBA(x, y, c)
+		return true
+	})
+}
+
+// Difference holds the changes required to turn one sparse image into another.
+type Difference struct {
+	// Added holds the pixels which have been drawn, which weren't drawn before.
+	Added *Image
+	// Changed holds the new colors of the pixels which were drawn before, but in a
+	// different color.
+	Changed *Image
+	// Removed holds the previous colors of the pixels which are no longer drawn.
+	Removed *Image
+}
+
+// Diff compares the images, returning the pixels which have been added, changed and
+// removed to get from the from image to the to image. Colors are compared as color.RGBA,
+// so images with different color models can be compared. The Added and Changed images use
+// the color model of the to image, and Removed uses the color model of the from image.
+func Diff(from, to *Image) Difference {
+	var d Difference
+	d.Compare(from, to)
+	return d
+}
+
+// Compare replaces the contents of the Difference with the changes required to get from the
+// from image to the to image, like Diff. The images of the Difference are cleared and
+// reused, so that comparing frames doesn't need to allocate memory each time.
+func (d *Difference) Compare(from, to *Image) {
+	d.Added = reuse(d.Added, to.bounds, to.model)
+	d.Changed = reuse(d.Changed, to.bounds, to.model)
+	d.Removed = reuse(d.Removed, from.bounds, from.model)
+
+	// Colors stored in the same way can be compared without converting them.
+	raw := from.kind == to.kind && from.kind != palettedKind
+
+	// Walk through the sorted keys of both images together.
+	i, j := 0, 0
+	for i < len(from.keys) || j < len(to.keys) {
+		switch {
+		case j == len(to.keys) || (i < len(from.keys) && keyLess(from.keys[i], to.keys[j])):
+			d.compareTile(from.keys[i], from, to, raw)
+			i++
+		case i == len(from.keys) || keyLess(to.keys[j], from.keys[i]):
+			d.compareTile(to.keys[j], from, to, raw)
+			j++
+		default:
+			d.compareTile(from.keys[i], from, to, raw)
+			i++
+			j++
+		}
+	}
+}
+
+// compareTile compares the tiles of the images with the key.
+func (d *Difference) compareTile(key image.Point, from, to *Image, raw bool) {
+	ft, tt := from.tiles[key], to.tiles[key]
+	if ft == nil {
+		ft = &emptyTile
+	}
+	if tt == nil {
+		tt = &emptyTile
+	}
+	if raw && ft.drawn == tt.drawn && ft.pix == tt.pix {
+		return
+	}
+	for w := range ft.drawn {
+		fd, td := ft.drawn[w], tt.drawn[w]
+		for set := fd | td; set != 0; {
+			b := bits.TrailingZeros64(set)
+			set &^= 1 << uint(b)
+			i := w<<6 | b
+			x, y := key.X<<tileShift|i&tileMask, key.Y<<tileShift|i>>tileShift
+			switch {
+			case td&(1<<uint(b)) == 0:
+				d.Removed.setRaw(x, y, ft.pix[i])
+			case fd&(1<<uint(b)) == 0:
+				d.Added.setRaw(x, y, tt.pix[i])
+			case raw && ft.pix[i] != tt.pix[i]:
+				d.Changed.setRaw(x, y, tt.pix[i])
+			case !raw && from.toRGBA(ft.pix[i]) != to.toRGBA(tt.pix[i]):
+				d.Changed.setRaw(x, y, tt.pix[i])
+			}
+		}
+	}
+}
+
+// emptyTile stands in for tiles which don't exist.
+var emptyTile tile
+
+// keyLess returns true if the tile a comes before the tile b in scanline order.
+func keyLess(a, b image.Point) bool {
+	return a.Y < b.Y || (a.Y == b.Y && a.X < b.X)
+}
+
+// reuse clears the img and changes its bounds and color model, or creates a new image if
+// img is nil.
+func reuse(img *Image, bounds image.Rectangle, model color.Model) *Image {
+	if img == nil {
+		return NewImageWithModel(bounds, model)
+	}
+	// The images of a Difference are only written by Compare, so the keys can be truncated
+	// in place to avoid allocating them again.
+	img.clearTiles()
+	img.keys = img.keys[:0]
+	img.bounds = bounds
+	img.model = model
+	img.kind = kindOf(model)
+	return img
+}
+
+// Crop returns a new image, with bounds r, containing the drawn pixels within r.
+func (img *Image) Crop(r image.Rectangle) *Image {
+	c := NewImageWithModel(r, img.model)
+	img.eachRaw(func(x, y int, raw color.RGBA) bool {
+		if (image.Point{x, y}).In(r) {
+			c.setRaw(x, y, raw)
+		}
+		return true
+	})
+	return c
+}
+
+// Translate returns a new image, with the bounds and pixels moved by the offset.
+func (img *Image) Translate(offset image.Point) *Image {
+	t := NewImageWithModel(img.bounds.Add(offset), img.model)
+	img.eachRaw(func(x, y int, raw color.RGBA) bool {
+		t.setRaw(x+offset.X, y+offset.Y, raw)
+		return true
+	})
+	return t
+}
@@ -0,0 +1,214 @@
+package sparse
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/colornames"
+)
+
+func TestConversionToAndFromRGBA(t *testing.T) {
+	dense := image.NewRGBA(image.Rect(-5, -5, 5, 5))
+	dense.Set(-5, -5, colornames.Red)
+	dense.Set(4, 4, colornames.Blue)
+	dense.Set(0, 0, color.NRGBA{0xff, 0xff, 0xff, 0x80})
+
+	img := FromImage(dense)
+	if img.Len() != 3 {
+		t.Errorf("expected only the 3 non-transparent pixels to be drawn, got %d", img.Len())
+	}
+	if img.Bounds() != dense.Bounds() {
+		t.Errorf("expected bounds %v, got %v", dense.Bounds(), img.Bounds())
+	}
+
+	// Pixels outside of the bounds are dropped when converting back.
+	img.Set(100, 100, colornames.White)
+	back := img.RGBA()
+	if back.Bounds() != dense.Bounds() {
+		t.Errorf("expected bounds %v, got %v", dense.Bounds(), back.Bounds())
+	}
+	for y := -5; y < 5; y++ {
+		for x := -5; x < 5; x++ {
+			if back.At(x, y) != dense.At(x, y) {
+				t.Errorf("{%d, %d}: expected %v, got %v", x, y, dense.At(x, y), back.At(x, y))
+			}
+		}
+	}
+
+	// Other image types are converted.
+	uniform := image.NewUniform(colornames.Green)
+	if c := FromImage(image.NewNRGBA(image.Rect(0, 0, 2, 2))); c.Len() != 0 {
+		t.Errorf("expected transparent pixels to be skipped, got %d", c.Len())
+	}
+	gray := image.NewGray(image.Rect(0, 0, 2, 2))
+	draw.Draw(gray, gray.Bounds(), uniform, image.ZP, draw.Src)
+	if c := FromImage(gray); c.Len() != 4 {
+		t.Errorf("expected all of the gray pixels to be drawn, got %d", c.Len())
+	}
+}
+
+func TestClone(t *testing.T) {
+	img := NewImage(image.Rect(0, 0, 10, 10))
+	img.Set(1, 1, colornames.Red)
+
+	c := img.Clone()
+	c.Set(1, 1, colornames.Blue)
+	c.Set(2, 2, colornames.Blue)
+
+	if img.At(1, 1) != colornames.Red || img.Len() != 1 {
+		t.Error("expected changes to the clone not to affect the original")
+	}
+	if c.At(1, 1) != colornames.Blue || c.Len() != 2 {
+		t.Error("expected the clone to be changed")
+	}
+}
+
+func TestThatClearingAClonedImageDoesNotAffectTheOther(t *testing.T) {
+	for _, clearClone := range []bool{true, false} {
+		img := NewImage(image.Rect(0, 0, 200, 200))
+		img.Set(10, 10, colornames.Red)
+		img.Set(50, 50, colornames.Red)
+		c := img.Clone()
+
+		cleared, kept := img, c
+		if clearClone {
+			cleared, kept = c, img
+		}
+		cleared.Clear()
+		cleared.Set(100, 100, colornames.Blue)
+
+		var visited []image.Point
+		kept.Each(func(x, y int, _ color.RGBA) bool {
+			visited = append(visited, image.Point{x, y})
+			return true
+		})
+		expected := []image.Point{{10, 10}, {50, 50}}
+		if !reflect.DeepEqual(visited, expected) {
+			t.Errorf("clearing the clone %v: expected %v to be visited, got %v", clearClone, expected, visited)
+		}
+		if cleared.Len() != 1 || cleared.At(100, 100) != colornames.Blue {
+			t.Errorf("clearing the clone %v: expected only the new pixel to be drawn", clearClone)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	halfWhite := color.RGBA{0x80, 0x80, 0x80, 0x80}
+
+	tests := []struct {
+		name     string
+		op       draw.Op
+		expected color.RGBA
+	}{
+		{
+			name:     "src replaces",
+			op:       draw.Src,
+			expected: halfWhite,
+		},
+		{
+			name:     "over blends",
+			op:       draw.Over,
+			expected: color.RGBA{0x80, 0x80, 0xff, 0xff},
+		},
+	}
+
+	for _, test := range tests {
+		dst := NewImage(image.Rect(0, 0, 10, 10))
+		dst.Set(0, 0, colornames.Blue)
+		src := NewImage(image.Rect(0, 0, 10, 10))
+		src.Set(0, 0, halfWhite)
+		src.Set(1, 1, halfWhite)
+
+		dst.Merge(src, test.op)
+
+		if dst.At(0, 0) != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, dst.At(0, 0))
+		}
+		if dst.At(1, 1) != halfWhite {
+			t.Errorf("%s: where nothing was drawn, expected %v, got %v", test.name, halfWhite, dst.At(1, 1))
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	from := NewImage(image.Rect(0, 0, 10, 10))
+	from.Set(0, 0, colornames.Red)
+	from.Set(1, 0, colornames.Red)
+	from.Set(2, 0, colornames.Red)
+
+	to := NewImage(image.Rect(0, 0, 10, 10))
+	to.Set(1, 0, colornames.Red)
+	to.Set(2, 0, colornames.Green)
+	to.Set(3, 0, colornames.Blue)
+
+	d := Diff(from, to)
+
+	if d.Removed.Len() != 1 || d.Removed.At(0, 0) != colornames.Red {
+		t.Errorf("expected {0, 0} to be removed, with its previous color")
+	}
+	if d.Changed.Len() != 1 || d.Changed.At(2, 0) != colornames.Green {
+		t.Errorf("expected {2, 0} to be changed to green")
+	}
+	if d.Added.Len() != 1 || d.Added.At(3, 0) != colornames.Blue {
+		t.Errorf("expected {3, 0} to be added")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	from := NewImage(image.Rect(0, 0, 100, 100))
+	to := NewImage(image.Rect(0, 0, 100, 100))
+	for x := 0; x < 100; x++ {
+		from.Set(x, x, colornames.Red)
+		to.Set(x, x, colornames.Red)
+	}
+	// A tile which is only in from, one which is only in to, and a changed pixel.
+	from.Set(99, 0, colornames.Red)
+	to.Set(0, 99, colornames.Blue)
+	to.Set(50, 50, colornames.Green)
+
+	var d Difference
+	for i := 0; i < 2; i++ {
+		d.Compare(from, to)
+		if d.Removed.Len() != 1 || d.Removed.At(99, 0) != colornames.Red {
+			t.Errorf("%d: expected {99, 0} to be removed", i)
+		}
+		if d.Added.Len() != 1 || d.Added.At(0, 99) != colornames.Blue {
+			t.Errorf("%d: expected {0, 99} to be added", i)
+		}
+		if d.Changed.Len() != 1 || d.Changed.At(50, 50) != colornames.Green {
+			t.Errorf("%d: expected {50, 50} to be changed", i)
+		}
+	}
+
+	allocs := testing.AllocsPerRun(10, func() {
+		d.Compare(from, to)
+	})
+	if allocs > 0 {
+		t.Errorf("expected comparing into a Difference to reuse its memory, got %v allocations", allocs)
+	}
+}
+
+func TestCropAndTranslate(t *testing.T) {
+	img := NewImage(image.Rect(0, 0, 10, 10))
+	img.Set(1, 1, colornames.Red)
+	img.Set(5, 5, colornames.Blue)
+
+	cropped := img.Crop(image.Rect(4, 4, 10, 10))
+	if cropped.Bounds() != image.Rect(4, 4, 10, 10) {
+		t.Errorf("expected the cropped bounds to be %v, got %v", image.Rect(4, 4, 10, 10), cropped.Bounds())
+	}
+	if cropped.Len() != 1 || cropped.At(5, 5) != colornames.Blue {
+		t.Error("expected only the blue pixel to remain after cropping")
+	}
+
+	moved := img.Translate(image.Point{-2, 3})
+	if moved.Bounds() != image.Rect(-2, 3, 8, 13) {
+		t.Errorf("expected the translated bounds to be %v, got %v", image.Rect(-2, 3, 8, 13), moved.Bounds())
+	}
+	if moved.At(-1, 4) != colornames.Red || moved.At(3, 8) != colornames.Blue || moved.Len() != 2 {
+		t.Error("expected the pixels to be moved")
+	}
+}
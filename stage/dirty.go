@@ -0,0 +1,99 @@
+package stage
+
+import (
+	"image"
+	"sort"
+
+	"github.com/a-h/raster/sparse"
+)
+
+// dirtyRectangles returns the areas which contain the pixels which have been added, changed
+// or removed. Rectangles which overlap or touch are merged together, so that each returned
+// rectangle covers a group of connected pixels.
+func dirtyRectangles(changes sparse.Difference) []image.Rectangle {
+	var spans []image.Rectangle
+	add := func(s sparse.Span) bool {
+		spans = append(spans, image.Rect(s.X, s.Y, s.X+len(s.Pix), s.Y+1))
+		return true
+	}
+	changes.Added.EachSpan(add)
+	changes.Changed.EachSpan(add)
+	changes.Removed.EachSpan(add)
+	return mergeSpans(spans)
+}
+
+// mergeSpans merges rows of pixels into rectangles. The rows are swept from top to bottom,
+// growing the rectangles which are still open, so that each row is only compared with the
+// rectangles which reach the row above it. A final merge joins rectangles which only
+// touched once they had grown.
+func mergeSpans(spans []image.Rectangle) []image.Rectangle {
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].Min.Y != spans[j].Min.Y {
+			return spans[i].Min.Y < spans[j].Min.Y
+		}
+		return spans[i].Min.X < spans[j].Min.X
+	})
+	var closed, open []image.Rectangle
+	for i, s := range spans {
+		if s.Empty() {
+			continue
+		}
+		if i == 0 || s.Min.Y != spans[i-1].Min.Y {
+			// Close the rectangles which end above the previous row, since they can't
+			// touch this row, or any of the rows below it.
+			stillOpen := open[:0]
+			for _, r := range open {
+				if r.Max.Y < s.Min.Y {
+					closed = append(closed, r)
+					continue
+				}
+				stillOpen = append(stillOpen, r)
+			}
+			open = stillOpen
+		}
+		untouched := open[:0]
+		for _, r := range open {
+			if touches(r, s) {
+				s = s.Union(r)
+				continue
+			}
+			untouched = append(untouched, r)
+		}
+		open = append(untouched, s)
+	}
+	return mergeRectangles(append(closed, open...))
+}
+
+// mergeRectangles replaces rectangles which overlap or touch with the rectangle which
+// contains them both, until none of the rectangles overlap or touch.
+func mergeRectangles(rects []image.Rectangle) []image.Rectangle {
+	var merged []image.Rectangle
+	for _, r := range rects {
+		if r.Empty() {
+			continue
+		}
+		merged = append(merged, r)
+	}
+	for {
+		changed := false
+		for i := 0; i < len(merged); i++ {
+			for j := i + 1; j < len(merged); j++ {
+				if !touches(merged[i], merged[j]) {
+					continue
+				}
+				merged[i] = merged[i].Union(merged[j])
+				merged = append(merged[:j], merged[j+1:]...)
+				changed = true
+				j = i
+			}
+		}
+		if !changed {
+			return merged
+		}
+	}
+}
+
+// touches returns true if the rectangles overlap, or are next to each other.
+func touches(a, b image.Rectangle) bool {
+	return a.Min.X <= b.Max.X && b.Min.X <= a.Max.X && a.Min.Y <= b.Max.Y && b.Min.Y <= a.Max.Y
+}
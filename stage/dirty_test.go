@@ -0,0 +1,139 @@
+package stage
+
+import (
+	"image"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestMergeRectangles(t *testing.T) {
+	tests := []struct {
+		name     string
+		rects    []image.Rectangle
+		expected []image.Rectangle
+	}{
+		{
+			name:     "nothing",
+			rects:    nil,
+			expected: nil,
+		},
+		{
+			name:     "empty rectangles are dropped",
+			rects:    []image.Rectangle{{}, image.Rect(5, 5, 5, 10)},
+			expected: nil,
+		},
+		{
+			name:     "separate rectangles are kept",
+			rects:    []image.Rectangle{image.Rect(0, 0, 2, 2), image.Rect(10, 10, 12, 12)},
+			expected: []image.Rectangle{image.Rect(0, 0, 2, 2), image.Rect(10, 10, 12, 12)},
+		},
+		{
+			name:     "overlapping rectangles are merged",
+			rects:    []image.Rectangle{image.Rect(0, 0, 5, 5), image.Rect(3, 3, 8, 8)},
+			expected: []image.Rectangle{image.Rect(0, 0, 8, 8)},
+		},
+		{
+			name:     "rows of a shape are merged",
+			rects:    []image.Rectangle{image.Rect(2, 0, 4, 1), image.Rect(1, 1, 5, 2), image.Rect(2, 2, 4, 3)},
+			expected: []image.Rectangle{image.Rect(1, 0, 5, 3)},
+		},
+		{
+			name: "merging can join rectangles which were checked earlier",
+			rects: []image.Rectangle{
+				image.Rect(0, 0, 2, 2),
+				image.Rect(10, 0, 12, 2),
+				image.Rect(0, 5, 12, 6),
+				image.Rect(1, 2, 2, 5),
+				image.Rect(11, 2, 12, 5),
+			},
+			expected: []image.Rectangle{image.Rect(0, 0, 12, 6)},
+		},
+	}
+
+	for _, test := range tests {
+		actual := mergeRectangles(test.rects)
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestMergeSpans(t *testing.T) {
+	tests := []struct {
+		name     string
+		spans    []image.Rectangle
+		expected []image.Rectangle
+	}{
+		{
+			name:     "nothing",
+			spans:    nil,
+			expected: nil,
+		},
+		{
+			name: "the rows of a shape are merged",
+			spans: []image.Rectangle{
+				image.Rect(2, 0, 4, 1),
+				image.Rect(1, 1, 5, 2),
+				image.Rect(2, 2, 4, 3),
+			},
+			expected: []image.Rectangle{image.Rect(1, 0, 5, 3)},
+		},
+		{
+			name: "separate shapes are kept apart",
+			spans: []image.Rectangle{
+				image.Rect(0, 0, 2, 1), image.Rect(10, 0, 12, 1),
+				image.Rect(0, 1, 2, 2), image.Rect(10, 1, 12, 2),
+				image.Rect(0, 10, 2, 11),
+			},
+			expected: []image.Rectangle{image.Rect(0, 0, 2, 2), image.Rect(0, 10, 2, 11), image.Rect(10, 0, 12, 2)},
+		},
+		{
+			name: "the arms of a U are joined by its base",
+			spans: []image.Rectangle{
+				image.Rect(0, 0, 1, 1), image.Rect(10, 0, 11, 1),
+				image.Rect(0, 1, 1, 2), image.Rect(10, 1, 11, 2),
+				image.Rect(0, 2, 11, 3),
+			},
+			expected: []image.Rectangle{image.Rect(0, 0, 11, 3)},
+		},
+		{
+			name: "growing rectangles join rectangles which have been closed",
+			spans: []image.Rectangle{
+				image.Rect(0, 0, 1, 1),
+				image.Rect(3, 1, 4, 2),
+				image.Rect(3, 2, 4, 3),
+				image.Rect(1, 3, 4, 4),
+			},
+			expected: []image.Rectangle{image.Rect(0, 0, 4, 4)},
+		},
+	}
+
+	for _, test := range tests {
+		actual := mergeSpans(test.spans)
+		sort.Slice(actual, func(i, j int) bool {
+			return actual[i].Min.X < actual[j].Min.X || (actual[i].Min.X == actual[j].Min.X && actual[i].Min.Y < actual[j].Min.Y)
+		})
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, actual)
+		}
+	}
+}
+
+func BenchmarkMergeSpans(b *testing.B) {
+	// 100 sprites, 20 pixels square, spread across a 1000x1000 frame.
+	var spans []image.Rectangle
+	for i := 0; i < 100; i++ {
+		x, y := (i%10)*100, (i/10)*100
+		for row := 0; row < 20; row++ {
+			spans = append(spans, image.Rect(x, y+row, x+20, y+row+1))
+		}
+	}
+	rects := make([]image.Rectangle, len(spans))
+	for i := 0; i < b.N; i++ {
+		copy(rects, spans)
+		if merged := mergeSpans(rects); len(merged) != 100 {
+			b.Fatalf("expected 100 rectangles, got %d", len(merged))
+		}
+	}
+}
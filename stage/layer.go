@@ -0,0 +1,118 @@
+package stage
+
+import (
+	"image/color"
+	"image/draw"
+	"sort"
+
+	"github.com/a-h/raster/sparse"
+)
+
+// Layer is a named image which is composited with the other layers of the stage when the
+// stage is drawn, e.g. to keep scenery, sprites and a HUD separate.
+type Layer struct {
+	Name string
+	// Z is the position of the layer. Layers with a higher Z are drawn on top. Layers with a
+	// negative Z are drawn underneath the stage's NextFrame, other layers are drawn on top.
+	Z int
+	// Visible layers are drawn, hidden layers are skipped, but keep their contents.
+	Visible bool
+	// Opacity (0 to 1) fades the layer.
+	Opacity float64
+	// Static layers keep their contents after the stage is drawn, so that they only need to
+	// be drawn once. Other layers are cleared, ready for the next frame, like NextFrame.
+	Static bool
+	// Image holds the pixels of the layer.
+	Image *sparse.Image
+}
+
+// AddLayer adds a visible, fully opaque layer to the stage, or returns the existing layer
+// if one with the same name has already been added.
+func (stg *Stage) AddLayer(name string, z int) *Layer {
+	if l := stg.Layer(name); l != nil {
+		return l
+	}
+	l := &Layer{
+		Name:    name,
+		Z:       z,
+		Visible: true,
+		Opacity: 1,
+		Image:   newFrame(stg.Backdrop),
+	}
+	stg.layers = append(stg.layers, l)
+	return l
+}
+
+// Layer returns the layer with the name, or nil if there isn't one.
+func (stg *Stage) Layer(name string) *Layer {
+	for _, l := range stg.layers {
+		if l.Name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// RemoveLayer removes the layer with the name, returning false if there wasn't one.
+func (stg *Stage) RemoveLayer(name string) bool {
+	for i, l := range stg.layers {
+		if l.Name == name {
+			stg.layers = append(stg.layers[:i], stg.layers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Layers returns the layers in the order they're drawn, from the bottom up. Layers with the
+// same Z are drawn in the order they were added.
+func (stg *Stage) Layers() []*Layer {
+	layers := make([]*Layer, len(stg.layers))
+	copy(layers, stg.layers)
+	sort.SliceStable(layers, func(i, j int) bool {
+		return layers[i].Z < layers[j].Z
+	})
+	return layers
+}
+
+// compose draws the layers and the NextFrame onto the frame, in order.
+func (stg *Stage) compose(frame *sparse.Image) {
+	nextFrameDrawn := false
+	for _, l := range stg.Layers() {
+		if l.Z >= 0 && !nextFrameDrawn {
+			frame.Merge(stg.NextFrame, draw.Over)
+			nextFrameDrawn = true
+		}
+		l.drawOnto(frame)
+	}
+	if !nextFrameDrawn {
+		frame.Merge(stg.NextFrame, draw.Over)
+	}
+}
+
+// drawOnto draws the layer over the top of the frame.
+func (l *Layer) drawOnto(frame *sparse.Image) {
+	if !l.Visible || l.Opacity <= 0 {
+		return
+	}
+	if l.Opacity >= 1 {
+		frame.Merge(l.Image, draw.Over)
+		return
+	}
+	faded := sparse.NewImageWithModel(l.Image.Bounds(), l.Image.ColorModel())
+	l.Image.Each(func(x, y int, c color.RGBA) bool {
+		faded.SetRGBA(x, y, fade(c, l.Opacity))
+		return true
+	})
+	frame.Merge(faded, draw.Over)
+}
+
+// fade multiplies the premultiplied color by the opacity.
+func fade(c color.RGBA, opacity float64) color.RGBA {
+	return color.RGBA{
+		R: uint8(float64(c.R)*opacity + 0.5),
+		G: uint8(float64(c.G)*opacity + 0.5),
+		B: uint8(float64(c.B)*opacity + 0.5),
+		A: uint8(float64(c.A)*opacity + 0.5),
+	}
+}
@@ -0,0 +1,124 @@
+package stage
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"golang.org/x/image/colornames"
+)
+
+func TestLayersAreDrawnInZOrder(t *testing.T) {
+	bounds := image.Rect(0, 0, 10, 10)
+	stg := New(image.NewRGBA(bounds))
+	hud := stg.AddLayer("hud", 10)
+	scenery := stg.AddLayer("scenery", -10)
+	sprites := stg.AddLayer("sprites", 0)
+
+	if stg.AddLayer("hud", 5) != hud {
+		t.Errorf("expected adding a layer with an existing name to return the existing layer")
+	}
+	var names []string
+	for _, l := range stg.Layers() {
+		names = append(names, l.Name)
+	}
+	if len(names) != 3 || names[0] != "scenery" || names[1] != "sprites" || names[2] != "hud" {
+		t.Errorf("expected layers in Z order, got %v", names)
+	}
+
+	// Each layer covers part of the one beneath it.
+	for x := 0; x < 4; x++ {
+		scenery.Image.Set(x, 0, colornames.Green)
+	}
+	stg.NextFrame.Set(1, 0, colornames.Blue)
+	stg.NextFrame.Set(2, 0, colornames.Blue)
+	stg.NextFrame.Set(3, 0, colornames.Blue)
+	sprites.Image.Set(2, 0, colornames.Red)
+	sprites.Image.Set(3, 0, colornames.Red)
+	hud.Image.Set(3, 0, colornames.White)
+
+	target := image.NewRGBA(bounds)
+	stg.Draw(target)
+
+	expected := []color.Color{colornames.Green, colornames.Blue, colornames.Red, colornames.White, color.RGBA{}}
+	if actual := getRow(target)[:5]; !equal(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestLayerVisibilityAndOpacity(t *testing.T) {
+	bounds := image.Rect(0, 0, 10, 10)
+	backdrop := image.NewRGBA(bounds)
+	draw.Draw(backdrop, bounds, image.NewUniform(colornames.White), image.Point{}, draw.Src)
+	stg := New(backdrop)
+	hidden := stg.AddLayer("hidden", 0)
+	hidden.Visible = false
+	faded := stg.AddLayer("faded", 1)
+	faded.Opacity = 0.5
+
+	hidden.Image.Set(0, 0, colornames.Red)
+	faded.Image.Set(1, 0, colornames.Red)
+
+	target := image.NewRGBA(bounds)
+	stg.Draw(target)
+
+	if target.RGBAAt(0, 0) != colornames.White {
+		t.Errorf("expected hidden layers not to be drawn, got %v", target.RGBAAt(0, 0))
+	}
+	// Half of the red, over the white backdrop.
+	expected := color.RGBA{0xff, 0x7f, 0x7f, 0xff}
+	if target.RGBAAt(1, 0) != expected {
+		t.Errorf("expected the faded layer to be blended with the backdrop %v, got %v", expected, target.RGBAAt(1, 0))
+	}
+}
+
+func TestStaticLayersAreKeptBetweenFrames(t *testing.T) {
+	bounds := image.Rect(0, 0, 10, 10)
+	stg := New(image.NewRGBA(bounds))
+	hud := stg.AddLayer("hud", 1)
+	hud.Static = true
+	sprites := stg.AddLayer("sprites", 0)
+
+	hud.Image.Set(0, 0, colornames.White)
+	sprites.Image.Set(5, 5, colornames.Red)
+
+	target := image.NewRGBA(bounds)
+	stg.Draw(target)
+
+	if hud.Image.Len() != 1 {
+		t.Errorf("expected the static layer to be kept, but it has %d pixels", hud.Image.Len())
+	}
+	if sprites.Image.Len() != 0 {
+		t.Errorf("expected the sprite layer to be cleared, but it has %d pixels", sprites.Image.Len())
+	}
+
+	// Moving the sprite only redraws the sprite, the static layer is unchanged.
+	sprites.Image.Set(6, 5, colornames.Red)
+	dirty := stg.Draw(target)
+	expected := []image.Rectangle{image.Rect(5, 5, 7, 6)}
+	if len(dirty) != 1 || dirty[0] != expected[0] {
+		t.Errorf("expected dirty areas %v, got %v", expected, dirty)
+	}
+	if target.RGBAAt(0, 0) != colornames.White {
+		t.Errorf("expected the static layer to still be drawn, got %v", target.RGBAAt(0, 0))
+	}
+	if target.RGBAAt(5, 5) != (color.RGBA{}) {
+		t.Errorf("expected the sprite's old position to be restored, got %v", target.RGBAAt(5, 5))
+	}
+}
+
+func TestRemoveLayer(t *testing.T) {
+	stg := New(image.NewRGBA(image.Rect(0, 0, 10, 10)))
+	stg.AddLayer("a", 0)
+
+	if !stg.RemoveLayer("a") {
+		t.Errorf("expected the layer to be removed")
+	}
+	if stg.RemoveLayer("a") {
+		t.Errorf("expected a missing layer not to be removed")
+	}
+	if stg.Layer("a") != nil {
+		t.Errorf("expected the layer to be gone")
+	}
+}
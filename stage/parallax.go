@@ -0,0 +1,148 @@
+package stage
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/a-h/raster/internal/composite"
+)
+
+// ParallaxLayer is a backdrop image which scrolls at a different speed to the camera, so
+// that distant scenery appears to move more slowly than the foreground.
+type ParallaxLayer struct {
+	Image image.Image
+	// ScrollX and ScrollY are how far the layer scrolls for each pixel the camera scrolls,
+	// e.g. 0 keeps the layer fixed to the screen, 0.5 scrolls at half the speed, and 1
+	// scrolls with the world.
+	ScrollX, ScrollY float64
+	// RepeatX and RepeatY tile the image, so that it never runs out. Areas outside of an
+	// image which isn't repeated are transparent.
+	RepeatX, RepeatY bool
+}
+
+// AddParallax adds a backdrop layer on top of the Backdrop and any parallax layers which
+// have already been added.
+func (stg *Stage) AddParallax(img image.Image, scrollX, scrollY float64) *ParallaxLayer {
+	l := &ParallaxLayer{
+		Image:   img,
+		ScrollX: scrollX,
+		ScrollY: scrollY,
+	}
+	stg.Parallax = append(stg.Parallax, l)
+	return l
+}
+
+// offset returns how far the layer has scrolled, given the camera's scroll position.
+func (l *ParallaxLayer) offset(scroll image.Point) image.Point {
+	return image.Point{
+		X: int(math.Round(float64(scroll.X) * l.ScrollX)),
+		Y: int(math.Round(float64(scroll.Y) * l.ScrollY)),
+	}
+}
+
+// at returns the color of the layer at the point on the screen, relative to the top left of
+// the stage.
+func (l *ParallaxLayer) at(p image.Point) color.Color {
+	b := l.Image.Bounds()
+	if b.Empty() {
+		return color.Transparent
+	}
+	x, y := p.X+b.Min.X, p.Y+b.Min.Y
+	if l.RepeatX {
+		x = b.Min.X + mod(x-b.Min.X, b.Dx())
+	}
+	if l.RepeatY {
+		y = b.Min.Y + mod(y-b.Min.Y, b.Dy())
+	}
+	if !(image.Point{x, y}).In(b) {
+		return color.Transparent
+	}
+	return l.Image.At(x, y)
+}
+
+// mod returns a positive remainder, even when v is negative.
+func mod(v, n int) int {
+	return ((v % n) + n) % n
+}
+
+// draw draws the layer over the area r of the img, where the top left of r is the top left
+// of the stage. Repeated layers are drawn once for each time they fit into r.
+func (l *ParallaxLayer) draw(img draw.Image, r image.Rectangle, offset image.Point) {
+	b := l.Image.Bounds()
+	if b.Empty() {
+		return
+	}
+	// The top left of the first copy of the image, which may be above or to the left of r.
+	start := r.Min.Sub(offset)
+	if l.RepeatX {
+		start.X = r.Min.X - mod(offset.X, b.Dx())
+	}
+	if l.RepeatY {
+		start.Y = r.Min.Y - mod(offset.Y, b.Dy())
+	}
+	for y := start.Y; y < r.Max.Y; y += b.Dy() {
+		for x := start.X; x < r.Max.X; x += b.Dx() {
+			area := image.Rectangle{image.Point{x, y}, image.Point{x, y}.Add(b.Size())}.Intersect(r)
+			if !area.Empty() {
+				draw.Draw(img, area, l.Image, b.Min.Add(area.Min.Sub(image.Point{x, y})), draw.Over)
+			}
+			if !l.RepeatX {
+				break
+			}
+		}
+		if !l.RepeatY {
+			break
+		}
+	}
+}
+
+// scroll returns how far the camera has scrolled, or zero if there's no camera.
+func (stg *Stage) scroll() image.Point {
+	if stg.Camera == nil {
+		return image.Point{}
+	}
+	return stg.Camera.Scroll()
+}
+
+// parallaxOffsets returns the offset of each parallax layer.
+func (stg *Stage) parallaxOffsets() []image.Point {
+	scroll := stg.scroll()
+	offsets := make([]image.Point, len(stg.Parallax))
+	for i, l := range stg.Parallax {
+		offsets[i] = l.offset(scroll)
+	}
+	return offsets
+}
+
+// parallaxMoved returns true if the parallax layers have scrolled, been added or been
+// removed since they were last drawn.
+func (stg *Stage) parallaxMoved(offsets []image.Point) bool {
+	if len(offsets) != len(stg.drawnOffsets) {
+		return true
+	}
+	for i := range offsets {
+		if offsets[i] != stg.drawnOffsets[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// backdropAt returns the color of the Backdrop at the point, with the parallax layers which
+// have been drawn over the top.
+func (stg *Stage) backdropAt(x, y int) color.Color {
+	c := stg.Backdrop.At(x, y)
+	if len(stg.drawnOffsets) == 0 {
+		return c
+	}
+	p := image.Point{x, y}.Sub(stg.Backdrop.Bounds().Min)
+	for i, offset := range stg.drawnOffsets {
+		if i >= len(stg.Parallax) {
+			break
+		}
+		c = composite.Over(c, stg.Parallax[i].at(p.Add(offset)))
+	}
+	return c
+}
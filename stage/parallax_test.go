@@ -0,0 +1,117 @@
+package stage
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/a-h/raster/camera"
+
+	"golang.org/x/image/colornames"
+)
+
+// stripes creates an image with a red pixel at the start of each row, the rest are
+// transparent.
+func stripes(bounds image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		img.SetRGBA(bounds.Min.X, y, colornames.Red)
+	}
+	return img
+}
+
+func TestParallaxLayersScrollWithTheCamera(t *testing.T) {
+	bounds := image.Rect(0, 0, 20, 10)
+	backdrop := image.NewRGBA(bounds)
+	draw.Draw(backdrop, bounds, &image.Uniform{colornames.White}, image.ZP, draw.Src)
+	stg := New(backdrop)
+	stg.Camera = camera.New(bounds)
+
+	far := stg.AddParallax(stripes(image.Rect(0, 0, 5, 10)), 0.5, 0)
+	far.RepeatX = true
+
+	target := image.NewRGBA(bounds)
+	dirty := stg.Draw(target)
+	if len(dirty) != 1 || dirty[0] != bounds {
+		t.Errorf("first draw: expected the whole backdrop to be dirty, got %v", dirty)
+	}
+	for _, x := range []int{0, 5, 10, 15} {
+		if target.RGBAAt(x, 3) != colornames.Red {
+			t.Errorf("expected the repeated layer to be drawn at x=%d, got %v", x, target.RGBAAt(x, 3))
+		}
+	}
+	if target.RGBAAt(1, 3) != colornames.White {
+		t.Errorf("expected the backdrop to show through the transparent pixels, got %v", target.RGBAAt(1, 3))
+	}
+
+	// Scrolling the camera 4 pixels moves the layer 2 pixels.
+	stg.Camera.Position.X += 4
+	dirty = stg.Draw(target)
+	if len(dirty) != 1 || dirty[0] != bounds {
+		t.Errorf("after scrolling: expected the whole backdrop to be dirty, got %v", dirty)
+	}
+	for _, x := range []int{3, 8, 13, 18} {
+		if target.RGBAAt(x, 3) != colornames.Red {
+			t.Errorf("after scrolling: expected the layer to be drawn at x=%d, got %v", x, target.RGBAAt(x, 3))
+		}
+	}
+	if stg.At(0, 3) != colornames.White {
+		t.Errorf("after scrolling: expected At to return the backdrop, got %v", stg.At(0, 3))
+	}
+
+	// Without scrolling, nothing needs to be redrawn.
+	dirty = stg.Draw(target)
+	if len(dirty) != 0 {
+		t.Errorf("without scrolling: expected nothing to be dirty, got %v", dirty)
+	}
+}
+
+func TestParallaxLayersAreRestoredUnderSprites(t *testing.T) {
+	bounds := image.Rect(0, 0, 20, 10)
+	stg := New(image.NewRGBA(bounds))
+	stg.AddParallax(stripes(image.Rect(0, 0, 5, 10)), 0, 0)
+
+	target := image.NewRGBA(bounds)
+	stg.NextFrame.Set(0, 3, colornames.Blue)
+	stg.Draw(target)
+	if target.RGBAAt(0, 3) != colornames.Blue {
+		t.Errorf("expected the sprite to be drawn over the layer, got %v", target.RGBAAt(0, 3))
+	}
+
+	stg.Draw(target)
+	if target.RGBAAt(0, 3) != colornames.Red {
+		t.Errorf("expected the layer to be restored, got %v", target.RGBAAt(0, 3))
+	}
+	if target.RGBAAt(6, 3) != (color.RGBA{}) {
+		t.Errorf("expected the layer not to be repeated, got %v", target.RGBAAt(6, 3))
+	}
+}
+
+func TestThatDrawnParallaxLayersMatchTheBackdrop(t *testing.T) {
+	bounds := image.Rect(-3, 2, 17, 12)
+	backdrop := image.NewRGBA(bounds)
+	draw.Draw(backdrop, bounds, &image.Uniform{colornames.White}, image.ZP, draw.Src)
+	stg := New(backdrop)
+	stg.Camera = camera.New(bounds)
+
+	both := stg.AddParallax(stripes(image.Rect(2, 1, 5, 4)), 1, 1)
+	both.RepeatX, both.RepeatY = true, true
+	across := stg.AddParallax(stripes(image.Rect(0, 0, 4, 3)), 0.5, 0.5)
+	across.RepeatX = true
+	stg.AddParallax(stripes(image.Rect(1, 1, 6, 6)), 2, 0)
+
+	for _, scroll := range []image.Point{{0, 0}, {7, 3}, {-5, -8}} {
+		stg.Camera.Position = bounds.Min.Add(scroll)
+		target := image.NewRGBA(bounds)
+		stg.Draw(target)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				expected := color.RGBAModel.Convert(stg.backdropAt(x, y))
+				if actual := target.RGBAAt(x, y); actual != expected {
+					t.Fatalf("scroll %v: at %d, %d expected %v, got %v", scroll, x, y, expected, actual)
+				}
+			}
+		}
+	}
+}
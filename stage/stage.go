@@ -5,6 +5,9 @@ import (
 	"image/color"
 	"image/draw"
 
+	"github.com/a-h/raster"
+	"github.com/a-h/raster/camera"
+	"github.com/a-h/raster/internal/composite"
 	"github.com/a-h/raster/sparse"
 )
 
@@ -15,77 +18,125 @@ type Stage struct {
 	NextFrame    *sparse.Image
 	// Set to true when the first frame has been drawn.
 	frameDrawn bool
+	// layers are composited with the NextFrame, see AddLayer.
+	layers []*Layer
+	// composed is reused to hold the result of compositing the layers.
+	composed *sparse.Image
+	// Camera is optional. When it's set, the NextFrame and layers are drawn in world
+	// coordinates, and the Camera maps them onto the screen. The Backdrop stays fixed to
+	// the screen.
+	Camera *camera.Camera
+	// viewed is reused to hold the frame as seen by the camera.
+	viewed *sparse.Image
+	// Parallax layers are drawn over the Backdrop, scrolling with the Camera.
+	Parallax []*ParallaxLayer
+	// drawnOffsets are the offsets of the Parallax layers which are on the image.
+	drawnOffsets []image.Point
+	// changes is reused to hold the differences between the frames.
+	changes sparse.Difference
 }
 
 // New creates a new Stage, initialised with a backdrop.
 func New(backdrop image.Image) *Stage {
 	return &Stage{
 		Backdrop:     backdrop,
-		CurrentFrame: sparse.NewImage(backdrop.Bounds()),
-		NextFrame:    sparse.NewImage(backdrop.Bounds()),
+		CurrentFrame: newFrame(backdrop),
+		NextFrame:    newFrame(backdrop),
 	}
 }
 
+// newFrame creates a frame the size of the backdrop. Frames hold the colors of the sprites,
+// not the backdrop, so they use the color.RGBAModel, whatever the backdrop's model is.
+func newFrame(backdrop image.Image) *sparse.Image {
+	return sparse.NewImage(backdrop.Bounds())
+}
+
 // ResetCurrentFrame resets the frame that's currently on screen.
 func (stg *Stage) ResetCurrentFrame() {
-	stg.CurrentFrame = sparse.NewImage(stg.Backdrop.Bounds())
+	stg.CurrentFrame = newFrame(stg.Backdrop)
 }
 
 // ResetNextFrame clears the frame that's going to be drawn next.
 func (stg *Stage) ResetNextFrame() {
-	stg.NextFrame = sparse.NewImage(stg.Backdrop.Bounds())
+	stg.NextFrame = newFrame(stg.Backdrop)
 }
 
 // Draw unpaints draws pixels in the ToDraw field onto the Backdrop and adds them to the Drawn field.
 // At the end, the ToDraw field is wiped. It's assumed that nothing else alters the img parameter other
-// than the stage itself.
-func (stg *Stage) Draw(img draw.Image) (redraw bool) {
-	// If it's the first draw, draw the entire backdrop onto the image.
-	if !stg.frameDrawn {
-		b := stg.Backdrop.Bounds()
-		for y := 0; y < b.Dy(); y++ {
-			for x := 0; x < b.Dx(); x++ {
-				img.Set(x, y, stg.Backdrop.At(x, y))
-			}
-		}
+// than the stage itself. Pixels are written to the img in the same order each time.
+//
+// The areas of the img which have changed are returned, so that only those areas need to be
+// uploaded to the screen. The rectangles don't overlap, and nothing is returned if the frame
+// is the same as the last one. The first draw returns the bounds of the whole backdrop.
+//
+// If layers have been added, the NextFrame and the layers are composited together to make
+// the frame. Static layers are kept for the next frame, other layers are cleared.
+//
+// Parallax layers are drawn over the Backdrop. When they scroll, the whole backdrop is
+// redrawn, and the bounds of the whole backdrop are returned.
+func (stg *Stage) Draw(img draw.Image) (dirty []image.Rectangle) {
+	// If it's the first draw, or the parallax layers have moved, draw the entire backdrop
+	// onto the image.
+	offsets := stg.parallaxOffsets()
+	redrawAll := !stg.frameDrawn || stg.parallaxMoved(offsets)
+	if redrawAll {
+		stg.drawnOffsets = offsets
+		stg.drawBackdrop(img)
+		// Everything in the current frame has been drawn over.
+		stg.CurrentFrame.Clear()
 		stg.frameDrawn = true
-		// Tell the caller to redraw the whole frame.
-		redraw = true
 	}
 
-	// Set everything that's already been drawn back to the background color, but only
-	// only when the NextFrame isn't going to set it to another (or the same) color.
-	pixelsToSkip := make(map[image.Point]interface{})
-	for p, existingColor := range stg.CurrentFrame.Drawn {
-		if newColor, pixelWillBeRedrawn := stg.NextFrame.Drawn[p]; pixelWillBeRedrawn {
-			// The next pixel is the same color anyway, so skip it.
-			if newColor == existingColor {
-				pixelsToSkip[p] = true
-			}
-		} else {
-			// Set the pixel back to the backdrop color.
-			backgroundColor := stg.Backdrop.At(p.X, p.Y)
-			img.Set(p.X, p.Y, backgroundColor)
+	// spare is the frame which is swapped with the current frame once it's been drawn.
+	spare := &stg.NextFrame
+	if len(stg.layers) > 0 {
+		if stg.composed == nil {
+			stg.composed = newFrame(stg.Backdrop)
 		}
+		stg.compose(stg.composed)
+		spare = &stg.composed
 	}
-
-	// Draw the pixels that need it back out.
-	for p, c := range stg.NextFrame.Drawn {
-		// If it's already been drawn, or the color is the same as the backdrop, don't ask
-		// the image to set it again.
-		if _, shouldSkip := pixelsToSkip[p]; !shouldSkip {
-			img.Set(p.X, p.Y, c)
+	if stg.Camera != nil {
+		if stg.viewed == nil {
+			stg.viewed = newFrame(stg.Backdrop)
 		}
+		stg.Camera.Draw(stg.viewed, *spare)
+		spare = &stg.viewed
 	}
+	next := *spare
 
-	// Copy pixels from the next frame to the current frame to keep a representation
-	// of what should be on the image.
-	stg.ResetCurrentFrame()
-	for p, c := range stg.NextFrame.Drawn {
-		stg.CurrentFrame.Drawn[p] = c
+	// Set everything that's no longer drawn back to the background color, and draw the
+	// pixels which are new or have changed color. Pixels which are the same color in
+	// both frames are left alone. Colors are compared after conversion to the frame's
+	// color model, so setting the same color in a different model isn't a change.
+	stg.changes.Compare(stg.CurrentFrame, next)
+	changes := stg.changes
+	changes.Removed.Each(stg.restorer(img))
+	set := stg.drawer(img)
+	changes.Added.Each(set)
+	changes.Changed.Each(set)
+
+	if redrawAll {
+		// Tell the caller to redraw the whole frame.
+		dirty = mergeRectangles(append(dirtyRectangles(changes), stg.Backdrop.Bounds()))
+	} else {
+		dirty = dirtyRectangles(changes)
+	}
+
+	// The next frame is now what's on the image, so keep it as the current frame, and
+	// reuse the old current frame for the next frame.
+	stg.CurrentFrame, *spare = next, stg.CurrentFrame
+	for _, frame := range []*sparse.Image{stg.NextFrame, stg.composed, stg.viewed} {
+		if frame != nil {
+			frame.Clear()
+		}
+	}
+	for _, l := range stg.layers {
+		if !l.Static {
+			l.Image.Clear()
+		}
 	}
-	stg.ResetNextFrame()
-	return
+	return dirty
 }
 
 // Implementation of the image.Draw interface below.
@@ -105,14 +156,60 @@ func (stg *Stage) Bounds() image.Rectangle {
 // Set the pixel with x, y coordinates to the color c.
 // The results of this are essentially thrown away.
 func (stg *Stage) Set(x, y int, c color.Color) {
-	stg.CurrentFrame.Drawn[image.Point{x, y}] = c
+	stg.CurrentFrame.Set(x, y, c)
 }
 
 // At returns the color of the pixel at (x, y).
 func (stg *Stage) At(x, y int) color.Color {
-	p := image.Point{x, y}
-	if c, ok := stg.CurrentFrame.Drawn[p]; ok {
-		return c
+	if _, ok := stg.CurrentFrame.Lookup(x, y); ok {
+		return stg.CurrentFrame.At(x, y)
+	}
+	return stg.backdropAt(x, y)
+}
+
+// drawBackdrop draws the whole of the Backdrop, and the parallax layers, onto the img.
+func (stg *Stage) drawBackdrop(img draw.Image) {
+	b := stg.Backdrop.Bounds()
+	draw.Draw(img, b, stg.Backdrop, b.Min, draw.Src)
+	for i, offset := range stg.drawnOffsets {
+		if i >= len(stg.Parallax) {
+			break
+		}
+		stg.Parallax[i].draw(img, b, offset)
+	}
+}
+
+// restorer returns a function which sets pixels on the img back to the backdrop. When
+// both are *image.RGBA, and there aren't any parallax layers, the pixels are copied
+// directly.
+func (stg *Stage) restorer(img draw.Image) func(x, y int, c color.RGBA) bool {
+	dst, dstOK := img.(*image.RGBA)
+	src, srcOK := stg.Backdrop.(*image.RGBA)
+	if dstOK && srcOK && len(stg.Parallax) == 0 {
+		return func(x, y int, c color.RGBA) bool {
+			p := image.Point{x, y}
+			if p.In(dst.Rect) && p.In(src.Rect) {
+				di, si := dst.PixOffset(x, y), src.PixOffset(x, y)
+				copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+			}
+			return true
+		}
+	}
+	return func(x, y int, c color.RGBA) bool {
+		img.Set(x, y, stg.backdropAt(x, y))
+		return true
+	}
+}
+
+// drawer returns a function which draws the pixels of the frame onto the img. Pixels which
+// aren't fully opaque, e.g. those of faded layers, are drawn over the backdrop.
+func (stg *Stage) drawer(img draw.Image) func(x, y int, c color.RGBA) bool {
+	set := raster.Setter(img)
+	return func(x, y int, c color.RGBA) bool {
+		if c.A != 0xff {
+			c = color.RGBAModel.Convert(composite.Over(stg.backdropAt(x, y), c)).(color.RGBA)
+		}
+		set(x, y, c)
+		return true
 	}
-	return stg.Backdrop.At(x, y)
 }
@@ -4,8 +4,10 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
+	"reflect"
 	"testing"
 
+	"github.com/a-h/raster/camera"
 	"github.com/a-h/raster/sparse"
 
 	"golang.org/x/image/colornames"
@@ -99,6 +101,83 @@ func TestModifyingTheFrameManually(t *testing.T) {
 	}
 }
 
+func TestThatDrawReturnsTheChangedAreas(t *testing.T) {
+	bounds := image.Rect(0, 0, 100, 100)
+	stg := New(image.NewRGBA(bounds))
+	target := image.NewRGBA(bounds)
+
+	dirty := stg.Draw(target)
+	if !reflect.DeepEqual(dirty, []image.Rectangle{bounds}) {
+		t.Errorf("first draw: expected the whole backdrop to be dirty, got %v", dirty)
+	}
+
+	dirty = stg.Draw(target)
+	if len(dirty) != 0 {
+		t.Errorf("unchanged frame: expected nothing to be dirty, got %v", dirty)
+	}
+
+	stg.NextFrame.Set(10, 10, colornames.Red)
+	stg.NextFrame.Set(11, 10, colornames.Red)
+	stg.NextFrame.Set(50, 60, colornames.Red)
+	dirty = stg.Draw(target)
+	expected := []image.Rectangle{image.Rect(10, 10, 12, 11), image.Rect(50, 60, 51, 61)}
+	if !reflect.DeepEqual(dirty, expected) {
+		t.Errorf("added pixels: expected %v, got %v", expected, dirty)
+	}
+
+	// Move the pair of pixels right, and remove the single pixel. The pixel at (11, 10)
+	// stays the same, so it's not included.
+	stg.NextFrame.Set(11, 10, colornames.Red)
+	stg.NextFrame.Set(12, 10, colornames.Red)
+	dirty = stg.Draw(target)
+	expected = []image.Rectangle{image.Rect(10, 10, 11, 11), image.Rect(12, 10, 13, 11), image.Rect(50, 60, 51, 61)}
+	if !reflect.DeepEqual(dirty, expected) {
+		t.Errorf("moved pixels: expected %v, got %v", expected, dirty)
+	}
+}
+
+// countingImage counts the pixels which are set on it.
+type countingImage struct {
+	*image.Gray
+	count int
+}
+
+func (img *countingImage) Set(x, y int, c color.Color) {
+	img.count++
+	img.Gray.Set(x, y, c)
+}
+
+func TestThatSpritesKeepTheirColorsOverAGrayBackdrop(t *testing.T) {
+	bounds := image.Rect(0, 0, 10, 10)
+	stg := New(image.NewGray(bounds))
+
+	target := image.NewRGBA(bounds)
+	stg.Draw(target)
+
+	stg.NextFrame.Set(5, 5, colornames.Red)
+	stg.Draw(target)
+	if actual := target.RGBAAt(5, 5); actual != colornames.Red {
+		t.Errorf("expected the sprite to be red, got %v", actual)
+	}
+}
+
+func TestThatTheSameColorInADifferentModelIsNotAChange(t *testing.T) {
+	bounds := image.Rect(0, 0, 10, 10)
+	stg := New(image.NewGray(bounds))
+	target := &countingImage{Gray: image.NewGray(bounds)}
+	stg.Draw(target)
+
+	stg.NextFrame.Set(5, 5, color.Gray{0x80})
+	stg.Draw(target)
+
+	target.count = 0
+	stg.NextFrame.Set(5, 5, color.NRGBA{0x80, 0x80, 0x80, 0xff})
+	stg.Draw(target)
+	if target.count != 0 {
+		t.Errorf("expected no pixels to be redrawn, got %d", target.count)
+	}
+}
+
 func TestAnimationDeltas(t *testing.T) {
 	r := colornames.Red
 	g := colornames.Green
@@ -179,3 +258,80 @@ func drawPixels(pixels []color.Color, img draw.Image) {
 		img.Set(i, 0, p)
 	}
 }
+
+func BenchmarkStageDraw(b *testing.B) {
+	bounds := image.Rect(0, 0, 1000, 1000)
+	backdrop := image.NewRGBA(bounds)
+	draw.Draw(backdrop, bounds, &image.Uniform{colornames.White}, image.ZP, draw.Src)
+	stg := New(backdrop)
+	target := image.NewRGBA(bounds)
+	stg.Draw(target)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Move a 200x200 square across the stage.
+		offset := i % 800
+		for y := 400; y < 600; y++ {
+			for x := offset; x < offset+200; x++ {
+				stg.NextFrame.Set(x, y, colornames.Red)
+			}
+		}
+		stg.Draw(target)
+	}
+}
+
+func TestDrawingThroughACamera(t *testing.T) {
+	bounds := image.Rect(0, 0, 100, 100)
+	stg := New(image.NewRGBA(bounds))
+	stg.Camera = camera.New(bounds)
+	stg.Camera.Position = image.Point{500, 500}
+	target := image.NewRGBA(bounds)
+
+	// The frame is drawn in world coordinates.
+	stg.NextFrame.Set(500, 500, colornames.Red)
+	stg.Draw(target)
+	if target.RGBAAt(50, 50) != colornames.Red {
+		t.Errorf("expected the pixel at the camera position to be drawn in the center, got %v", target.RGBAAt(50, 50))
+	}
+
+	// Scrolling the camera moves the pixel on the screen.
+	stg.Camera.Position = image.Point{510, 500}
+	stg.NextFrame.Set(500, 500, colornames.Red)
+	dirty := stg.Draw(target)
+	if target.RGBAAt(40, 50) != colornames.Red {
+		t.Errorf("expected the pixel to move left, got %v", target.RGBAAt(40, 50))
+	}
+	if target.RGBAAt(50, 50) != (color.RGBA{}) {
+		t.Errorf("expected the old position to be restored, got %v", target.RGBAAt(50, 50))
+	}
+	expected := []image.Rectangle{image.Rect(40, 50, 41, 51), image.Rect(50, 50, 51, 51)}
+	if !reflect.DeepEqual(dirty, expected) {
+		t.Errorf("expected dirty areas %v, got %v", expected, dirty)
+	}
+}
+
+func BenchmarkStageFirstDraw(b *testing.B) {
+	bounds := image.Rect(0, 0, 1000, 1000)
+	backdrop := image.NewRGBA(bounds)
+	draw.Draw(backdrop, bounds, &image.Uniform{colornames.White}, image.ZP, draw.Src)
+	target := image.NewRGBA(bounds)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		New(backdrop).Draw(target)
+	}
+}
+
+func BenchmarkStageFirstDrawDrawImage(b *testing.B) {
+	bounds := image.Rect(0, 0, 1000, 1000)
+	backdrop := image.NewRGBA(bounds)
+	draw.Draw(backdrop, bounds, &image.Uniform{colornames.White}, image.ZP, draw.Src)
+	// There's no fast path for an *image.NRGBA, so it's drawn through the draw.Image
+	// interface.
+	target := image.NewNRGBA(bounds)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		New(backdrop).Draw(target)
+	}
+}
@@ -0,0 +1,210 @@
+// Package stream serves the frames of an animation over HTTP, so that a simulation running
+// on a server without a screen can be watched in a web browser.
+package stream
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"sync"
+)
+
+// Publisher implements the world.Publisher interface, serving the published frames as a
+// Motion JPEG stream, which most web browsers can display in an img tag.
+type Publisher struct {
+	// Quality of the JPEG images, from 1 to 100.
+	Quality int
+
+	m       sync.Mutex
+	latest  *frame
+	clients map[*client]struct{}
+	err     error
+}
+
+// frame is a published frame. It's only encoded once something needs the JPEG.
+type frame struct {
+	// number counts up from 1 for each published frame.
+	number  int
+	img     *image.RGBA
+	quality int
+
+	once sync.Once
+	jpeg []byte
+	err  error
+}
+
+// encode returns the frame as a JPEG, encoding it the first time it's needed.
+func (f *frame) encode() ([]byte, error) {
+	f.once.Do(func() {
+		var buf bytes.Buffer
+		f.err = jpeg.Encode(&buf, f.img, &jpeg.Options{Quality: f.quality})
+		f.jpeg = buf.Bytes()
+	})
+	return f.jpeg, f.err
+}
+
+// client is a connected stream. Only the most recent frame is kept for each client, so
+// frames are dropped if the client can't keep up, rather than slowing down the publisher.
+type client struct {
+	frames chan *frame
+}
+
+// New creates a Publisher.
+func New() *Publisher {
+	return &Publisher{
+		Quality: 75,
+		clients: make(map[*client]struct{}),
+	}
+}
+
+// Publish sends the img to the connected clients. The img is only encoded if there are
+// clients to send it to. Errors are reported by Err, see world.ErrorPublisher.
+func (p *Publisher) Publish(img draw.Image) {
+	b := img.Bounds()
+	copied := image.NewRGBA(b)
+	draw.Draw(copied, b, img, b.Min, draw.Src)
+
+	p.m.Lock()
+	f := &frame{img: copied, quality: p.Quality, number: 1}
+	if p.latest != nil {
+		f.number = p.latest.number + 1
+	}
+	p.latest = f
+	watched := len(p.clients) > 0
+	p.m.Unlock()
+
+	if !watched {
+		return
+	}
+	if _, err := f.encode(); err != nil {
+		p.fail(err)
+		return
+	}
+	p.m.Lock()
+	defer p.m.Unlock()
+	for c := range p.clients {
+		c.send(f)
+	}
+}
+
+// Err returns the first error which occurred while encoding the frames.
+func (p *Publisher) Err() error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.err
+}
+
+// fail keeps the err, unless an error has already occurred.
+func (p *Publisher) fail(err error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// send replaces any frame which the client hasn't received yet with f.
+func (c *client) send(f *frame) {
+	select {
+	case <-c.frames:
+	default:
+	}
+	c.frames <- f
+}
+
+func (p *Publisher) subscribe() *client {
+	c := &client{frames: make(chan *frame, 1)}
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.clients == nil {
+		p.clients = make(map[*client]struct{})
+	}
+	p.clients[c] = struct{}{}
+	if p.latest != nil {
+		c.send(p.latest)
+	}
+	return c
+}
+
+func (p *Publisher) unsubscribe(c *client) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	delete(p.clients, c)
+}
+
+// Handler returns a handler which serves the stream at /stream, and the most recent frame,
+// as a PNG image, at /snapshot.png.
+func (p *Publisher) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stream", p.ServeStream)
+	mux.HandleFunc("/snapshot.png", p.ServeSnapshot)
+	return mux
+}
+
+// ServeStream serves the frames as a multipart Motion JPEG stream, until the client
+// disconnects.
+func (p *Publisher) ServeStream(w http.ResponseWriter, r *http.Request) {
+	c := p.subscribe()
+	defer p.unsubscribe(c)
+
+	mw := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+mw.Boundary())
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case f := <-c.frames:
+			encoded, err := f.encode()
+			if err != nil {
+				p.fail(err)
+				continue
+			}
+			header := textproto.MIMEHeader{}
+			header.Set("Content-Type", "image/jpeg")
+			header.Set("Content-Length", fmt.Sprint(len(encoded)))
+			part, err := mw.CreatePart(header)
+			if err != nil {
+				return
+			}
+			if _, err := part.Write(encoded); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// ServeSnapshot serves the most recent frame as a PNG image. If nothing has been published
+// yet, it returns a 503 Service Unavailable error.
+func (p *Publisher) ServeSnapshot(w http.ResponseWriter, r *http.Request) {
+	p.m.Lock()
+	latest := p.latest
+	p.m.Unlock()
+	if latest == nil {
+		http.Error(w, "no frames have been published", http.StatusServiceUnavailable)
+		return
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, latest.img); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,172 @@
+package stream
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/image/colornames"
+)
+
+func filled(c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.ZP, draw.Src)
+	return img
+}
+
+// isClose allows for the loss of quality caused by JPEG compression.
+func isClose(c color.Color, expected color.RGBA) bool {
+	r, g, b, _ := c.RGBA()
+	near := func(v uint32, e uint8) bool {
+		d := int(v>>8) - int(e)
+		return d > -16 && d < 16
+	}
+	return near(r, expected.R) && near(g, expected.G) && near(b, expected.B)
+}
+
+func TestSnapshot(t *testing.T) {
+	p := New()
+	server := httptest.NewServer(p.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/snapshot.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("before publishing: expected status %d, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	p.Publish(filled(colornames.Red))
+	resp, err = http.Get(server.URL + "/snapshot.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	img, err := png.Decode(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if r, g, b, _ := img.At(8, 8).RGBA(); r != 0xffff || g != 0 || b != 0 {
+		t.Errorf("expected a red image, got %v", img.At(8, 8))
+	}
+}
+
+func TestStream(t *testing.T) {
+	p := New()
+	server := httptest.NewServer(p.Handler())
+	defer server.Close()
+
+	p.Publish(filled(colornames.Red))
+
+	resp, err := http.Get(server.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/x-mixed-replace" {
+		t.Fatalf("expected a multipart stream, got %q", resp.Header.Get("Content-Type"))
+	}
+	parts := multipart.NewReader(resp.Body, params["boundary"])
+
+	// The most recent frame is sent as soon as the client connects, then each new frame.
+	for i, expected := range []color.RGBA{colornames.Red, colornames.Blue} {
+		if i > 0 {
+			p.Publish(filled(expected))
+		}
+		part, err := parts.NextPart()
+		if err != nil {
+			t.Fatalf("frame %d: failed to read part: %v", i, err)
+		}
+		if part.Header.Get("Content-Type") != "image/jpeg" {
+			t.Errorf("frame %d: expected a JPEG, got %q", i, part.Header.Get("Content-Type"))
+		}
+		img, err := jpeg.Decode(part)
+		if err != nil {
+			t.Fatalf("frame %d: failed to decode: %v", i, err)
+		}
+		if !isClose(img.At(8, 8), expected) {
+			t.Errorf("frame %d: expected %v, got %v", i, expected, img.At(8, 8))
+		}
+	}
+
+	// Disconnecting removes the client.
+	resp.Body.Close()
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		p.m.Lock()
+		clients := len(p.clients)
+		p.m.Unlock()
+		if clients == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the client to be removed, but there are %d clients", clients)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestThatSlowClientsOnlyReceiveTheLatestFrame(t *testing.T) {
+	p := New()
+	c := p.subscribe()
+	defer p.unsubscribe(c)
+
+	for i := 0; i < 5; i++ {
+		p.Publish(filled(colornames.Red))
+	}
+
+	f := <-c.frames
+	if f.number != 5 {
+		t.Errorf("expected the latest frame to be received, got frame %d", f.number)
+	}
+	select {
+	case f := <-c.frames:
+		t.Errorf("expected the earlier frames to be dropped, got frame %d", f.number)
+	default:
+	}
+}
+
+func TestThatFramesAreOnlyEncodedForClients(t *testing.T) {
+	p := New()
+	p.Publish(filled(colornames.Red))
+	if p.latest.jpeg != nil {
+		t.Errorf("expected the frame not to be encoded without any clients")
+	}
+
+	c := p.subscribe()
+	defer p.unsubscribe(c)
+	p.Publish(filled(colornames.Blue))
+	if p.latest.jpeg == nil {
+		t.Errorf("expected the frame to be encoded for the client")
+	}
+}
+
+func TestThatTheFirstEncodingErrorIsKept(t *testing.T) {
+	p := New()
+	c := p.subscribe()
+	defer p.unsubscribe(c)
+
+	// JPEG images can't be wider than 65535 pixels.
+	p.Publish(image.NewRGBA(image.Rect(0, 0, 1<<16, 1)))
+	err := p.Err()
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	p.Publish(filled(colornames.Red))
+	if p.Err() != err {
+		t.Errorf("expected the first error to be kept, got %v", p.Err())
+	}
+}
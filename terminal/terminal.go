@@ -0,0 +1,153 @@
+// Package terminal draws images in a terminal, e.g. to preview an animation over SSH. Each
+// character cell shows two pixels, one above the other, using the Unicode upper half block
+// character with 24-bit ANSI colors.
+package terminal
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+)
+
+// upperHalfBlock is drawn in the foreground color, so the top pixel is the foreground
+// color and the bottom pixel is the background color.
+const upperHalfBlock = "▀"
+
+// cell is a character on the terminal.
+type cell struct {
+	top, bottom color.RGBA
+}
+
+// Publisher writes frames to a terminal. Only the cells which have changed since the
+// previous frame are written, so the terminal doesn't flicker. It implements the
+// world.Publisher interface.
+type Publisher struct {
+	// Columns and Rows are the size of the area to draw in. Each row shows two rows of
+	// pixels. The image is scaled to fit. If they're zero, the image isn't scaled.
+	Columns, Rows int
+
+	w     io.Writer
+	cells []cell
+	size  image.Point
+	err   error
+}
+
+// New creates a Publisher which writes to w, e.g. os.Stdout.
+func New(w io.Writer) *Publisher {
+	return &Publisher{
+		w: w,
+	}
+}
+
+// Publish draws the img. Errors are reported by Err, see world.ErrorPublisher.
+func (p *Publisher) Publish(img draw.Image) {
+	if p.err != nil {
+		return
+	}
+	p.err = p.Render(img)
+}
+
+// Err returns the first error which occurred while publishing.
+func (p *Publisher) Err() error {
+	return p.err
+}
+
+// scaled returns true if the image is scaled to fit the Columns and Rows.
+func (p *Publisher) scaled() bool {
+	return p.Columns > 0 && p.Rows > 0
+}
+
+// sizeOf returns the number of columns and rows used to draw the image.
+func (p *Publisher) sizeOf(b image.Rectangle) image.Point {
+	if p.scaled() {
+		return image.Point{p.Columns, p.Rows}
+	}
+	return image.Point{b.Dx(), (b.Dy() + 1) / 2}
+}
+
+// Render draws the img to the terminal, writing only the cells which have changed since the
+// last call. Semi-transparent pixels are drawn over black.
+func (p *Publisher) Render(img image.Image) error {
+	b := img.Bounds()
+	size := p.sizeOf(b)
+	// Map the terminal pixels to the image's pixels.
+	at := func(x, y int) color.RGBA {
+		return sample(img, image.Point{b.Min.X + x, b.Min.Y + y})
+	}
+	if p.scaled() {
+		at = func(x, y int) color.RGBA {
+			return sample(img, image.Point{b.Min.X + x*b.Dx()/size.X, b.Min.Y + y*b.Dy()/(size.Y*2)})
+		}
+	}
+	cells := make([]cell, size.X*size.Y)
+	for row := 0; row < size.Y; row++ {
+		for col := 0; col < size.X; col++ {
+			cells[row*size.X+col] = cell{
+				top:    at(col, row*2),
+				bottom: at(col, row*2+1),
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(p.w)
+	redraw := size != p.size
+	if redraw {
+		// Clear the screen, and hide the cursor.
+		fmt.Fprint(bw, "\x1b[2J\x1b[?25l")
+	}
+
+	// Keep track of the cursor position and colors, to avoid writing them when they're
+	// already correct.
+	cursor := image.Point{-1, -1}
+	var fg, bg *color.RGBA
+	for i, c := range cells {
+		if !redraw && c == p.cells[i] {
+			continue
+		}
+		pos := image.Point{i % size.X, i / size.X}
+		if pos != cursor {
+			// ANSI positions start at 1.
+			fmt.Fprintf(bw, "\x1b[%d;%dH", pos.Y+1, pos.X+1)
+		}
+		if fg == nil || *fg != c.top {
+			fmt.Fprintf(bw, "\x1b[38;2;%d;%d;%dm", c.top.R, c.top.G, c.top.B)
+			fg = &cells[i].top
+		}
+		if bg == nil || *bg != c.bottom {
+			fmt.Fprintf(bw, "\x1b[48;2;%d;%d;%dm", c.bottom.R, c.bottom.G, c.bottom.B)
+			bg = &cells[i].bottom
+		}
+		fmt.Fprint(bw, upperHalfBlock)
+		cursor = image.Point{pos.X + 1, pos.Y}
+		if cursor.X == size.X {
+			// The terminal may wrap, so the next position must be written.
+			cursor = image.Point{-1, -1}
+		}
+	}
+	if fg != nil {
+		// Reset the colors, so that anything else written to the terminal is unaffected.
+		fmt.Fprint(bw, "\x1b[0m")
+	}
+	p.cells, p.size = cells, size
+	return bw.Flush()
+}
+
+// sample returns the color of the image at the point, or black if it's outside the image.
+func sample(img image.Image, p image.Point) color.RGBA {
+	if !p.In(img.Bounds()) {
+		return color.RGBA{A: 0xff}
+	}
+	c := color.RGBAModel.Convert(img.At(p.X, p.Y)).(color.RGBA)
+	// The colors are premultiplied, so they're already drawn over black.
+	c.A = 0xff
+	return c
+}
+
+// Close shows the cursor and resets the colors.
+func (p *Publisher) Close() error {
+	_, err := fmt.Fprint(p.w, "\x1b[0m\x1b[?25h")
+	return err
+}
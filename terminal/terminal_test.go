@@ -0,0 +1,114 @@
+package terminal
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/colornames"
+)
+
+func TestRender(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 3))
+	img.SetRGBA(0, 0, colornames.Red)
+	img.SetRGBA(0, 1, colornames.Blue)
+	img.SetRGBA(1, 0, colornames.Red)
+	img.SetRGBA(1, 1, colornames.Blue)
+	img.SetRGBA(0, 2, colornames.White)
+
+	var buf bytes.Buffer
+	p := New(&buf)
+	if err := p.Render(img); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "\x1b[2J\x1b[?25l" +
+		// The first row, the colors are the same for both cells.
+		"\x1b[1;1H\x1b[38;2;255;0;0m\x1b[48;2;0;0;255m▀▀" +
+		// The second row, the bottom half is outside of the image, so it's black.
+		"\x1b[2;1H\x1b[38;2;255;255;255m\x1b[48;2;0;0;0m▀\x1b[38;2;0;0;0m▀" +
+		"\x1b[0m"
+	if actual := buf.String(); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestThatOnlyChangedCellsAreWritten(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	var buf bytes.Buffer
+	p := New(&buf)
+	p.Render(img)
+
+	buf.Reset()
+	p.Render(img)
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing to be written for an unchanged frame, got %q", buf.String())
+	}
+
+	img.SetRGBA(2, 3, colornames.Green)
+	p.Render(img)
+	expected := "\x1b[2;3H\x1b[38;2;0;0;0m\x1b[48;2;0;128;0m▀\x1b[0m"
+	if actual := buf.String(); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestScaling(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	for x := 50; x < 100; x++ {
+		for y := 0; y < 100; y++ {
+			img.SetRGBA(x, y, colornames.White)
+		}
+	}
+	var buf bytes.Buffer
+	p := New(&buf)
+	p.Columns, p.Rows = 4, 2
+	p.Render(img)
+
+	if count := strings.Count(buf.String(), upperHalfBlock); count != 8 {
+		t.Errorf("expected 8 cells to be drawn, got %d", count)
+	}
+	if len(p.cells) != 8 || p.cells[0].top != (color.RGBA{A: 0xff}) || p.cells[3].top != colornames.White {
+		t.Errorf("expected the left half to be black, and the right half white, got %v", p.cells)
+	}
+}
+
+func TestThatSemiTransparentPixelsAreDrawnOverBlack(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 2))
+	img.SetRGBA(0, 0, color.RGBA{0x80, 0, 0, 0x80})
+	p := New(&bytes.Buffer{})
+	p.Render(img)
+	if expected := (color.RGBA{0x80, 0, 0, 0xff}); p.cells[0].top != expected {
+		t.Errorf("expected %v, got %v", expected, p.cells[0].top)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errTest
+}
+
+var errTest = errors.New("write failed")
+
+func TestPublishKeepsTheFirstError(t *testing.T) {
+	p := New(failingWriter{})
+	p.Publish(image.NewRGBA(image.Rect(0, 0, 1, 1)))
+	if p.Err() != errTest {
+		t.Errorf("expected the write error, got %v", p.Err())
+	}
+}
+
+func TestClose(t *testing.T) {
+	var buf bytes.Buffer
+	p := New(&buf)
+	if err := p.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "\x1b[0m\x1b[?25h"; buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,102 @@
+package tween
+
+import "math"
+
+// Easing maps the linear progress of an animation (0 to 1) to the eased progress, e.g. to
+// start slowly and speed up. The eased progress may go outside of the range 0 to 1, e.g.
+// an elastic easing overshoots the target before settling.
+type Easing func(t float64) float64
+
+// Linear progresses at a constant rate.
+func Linear(t float64) float64 {
+	return t
+}
+
+// EaseInQuad starts slowly and accelerates.
+func EaseInQuad(t float64) float64 {
+	return t * t
+}
+
+// EaseOutQuad starts quickly and decelerates.
+func EaseOutQuad(t float64) float64 {
+	return 1 - EaseInQuad(1-t)
+}
+
+// EaseInOutQuad accelerates until half way, then decelerates.
+func EaseInOutQuad(t float64) float64 {
+	return inOut(EaseInQuad, t)
+}
+
+// EaseInCubic starts slowly and accelerates, more sharply than EaseInQuad.
+func EaseInCubic(t float64) float64 {
+	return t * t * t
+}
+
+// EaseOutCubic starts quickly and decelerates, more sharply than EaseOutQuad.
+func EaseOutCubic(t float64) float64 {
+	return 1 - EaseInCubic(1-t)
+}
+
+// EaseInOutCubic accelerates until half way, then decelerates.
+func EaseInOutCubic(t float64) float64 {
+	return inOut(EaseInCubic, t)
+}
+
+// EaseInElastic winds up, oscillating with increasing size, before springing to the end.
+func EaseInElastic(t float64) float64 {
+	return 1 - EaseOutElastic(1-t)
+}
+
+// EaseOutElastic overshoots the end and oscillates like a spring before settling.
+func EaseOutElastic(t float64) float64 {
+	if t <= 0 || t >= 1 {
+		return clamp(t)
+	}
+	const period = 0.3
+	return math.Pow(2, -10*t)*math.Sin((t-period/4)*(2*math.Pi)/period) + 1
+}
+
+// EaseInOutElastic winds up, then overshoots and settles.
+func EaseInOutElastic(t float64) float64 {
+	return inOut(EaseInElastic, t)
+}
+
+// EaseInBounce bounces with increasing height before leaving the start.
+func EaseInBounce(t float64) float64 {
+	return 1 - EaseOutBounce(1-t)
+}
+
+// EaseOutBounce falls to the end and bounces with decreasing height, like a dropped ball.
+func EaseOutBounce(t float64) float64 {
+	const n, d = 7.5625, 2.75
+	switch {
+	case t < 1/d:
+		return n * t * t
+	case t < 2/d:
+		t -= 1.5 / d
+		return n*t*t + 0.75
+	case t < 2.5/d:
+		t -= 2.25 / d
+		return n*t*t + 0.9375
+	}
+	t -= 2.625 / d
+	return n*t*t + 0.984375
+}
+
+// EaseInOutBounce bounces away from the start, and into the end.
+func EaseInOutBounce(t float64) float64 {
+	return inOut(EaseInBounce, t)
+}
+
+// inOut uses the first half of the easing function to ease in, and a mirror image of it to
+// ease out.
+func inOut(in Easing, t float64) float64 {
+	if t < 0.5 {
+		return in(t*2) / 2
+	}
+	return 1 - in((1-t)*2)/2
+}
+
+func clamp(t float64) float64 {
+	return math.Max(0, math.Min(1, t))
+}
@@ -0,0 +1,71 @@
+package tween
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/a-h/raster/affine"
+	"github.com/a-h/round"
+)
+
+// Float returns the value which is the proportion t of the way from a to b.
+func Float(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+// Int returns the value which is the proportion t of the way from a to b, rounded to the
+// nearest integer.
+func Int(a, b int, t float64) int {
+	return int(round.ToEven(Float(float64(a), float64(b), t), 0))
+}
+
+// Point returns the point which is the proportion t of the way from a to b.
+func Point(a, b image.Point, t float64) image.Point {
+	return image.Point{Int(a.X, b.X, t), Int(a.Y, b.Y, t)}
+}
+
+// Color blends the colors, returning the color which is the proportion t of the way from
+// a to b.
+func Color(a, b color.Color, t float64) color.RGBA {
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	channel := func(x, y uint32) uint8 {
+		v := Float(float64(x), float64(y), t) / 0x101
+		return uint8(math.Max(0, math.Min(0xff, math.Round(v))))
+	}
+	return color.RGBA{
+		R: channel(ar, br),
+		G: channel(ag, bg),
+		B: channel(ab, bb),
+		A: channel(aa, ba),
+	}
+}
+
+// Angle returns the angle (in degrees) which is the proportion t of the way from a to b,
+// travelling in the shortest direction around the circle.
+func Angle(a, b, t float64) float64 {
+	delta := math.Mod(b-a, 360)
+	if delta > 180 {
+		delta -= 360
+	}
+	if delta < -180 {
+		delta += 360
+	}
+	return a + delta*t
+}
+
+// Transformation returns the transformation which is the proportion t of the way from a to
+// b. The transformations are decomposed into translation, rotation, scale and skew, which
+// are interpolated separately, so that e.g. a rotation turns instead of shrinking and growing.
+func Transformation(a, b affine.Transformation, t float64) affine.Transformation {
+	da, db := a.Decompose(), b.Decompose()
+	return affine.Decomposition{
+		TranslateX: Float(da.TranslateX, db.TranslateX, t),
+		TranslateY: Float(da.TranslateY, db.TranslateY, t),
+		Rotation:   Angle(da.Rotation, db.Rotation, t),
+		ScaleX:     Float(da.ScaleX, db.ScaleX, t),
+		ScaleY:     Float(da.ScaleY, db.ScaleY, t),
+		Skew:       Float(da.Skew, db.Skew, t),
+	}.Transformation()
+}
@@ -0,0 +1,106 @@
+// Package tween animates values over time, e.g. to move, rotate or fade a raster.Composition.
+package tween
+
+import (
+	"image"
+	"time"
+
+	"github.com/a-h/raster"
+	"github.com/a-h/raster/affine"
+)
+
+// Tween tracks the progress of an animation which lasts for a fixed duration.
+type Tween struct {
+	Duration time.Duration
+	Easing   Easing
+	Elapsed  time.Duration
+}
+
+// New creates a Tween. If easing is nil, Linear is used.
+func New(duration time.Duration, easing Easing) *Tween {
+	if easing == nil {
+		easing = Linear
+	}
+	return &Tween{
+		Duration: duration,
+		Easing:   easing,
+	}
+}
+
+// Advance moves the animation forward by d, returning true when the animation has finished.
+func (tw *Tween) Advance(d time.Duration) (done bool) {
+	tw.Elapsed += d
+	if tw.Elapsed > tw.Duration {
+		tw.Elapsed = tw.Duration
+	}
+	return tw.Done()
+}
+
+// Done returns true when the animation has finished.
+func (tw *Tween) Done() bool {
+	return tw.Elapsed >= tw.Duration
+}
+
+// Reset moves the animation back to the start.
+func (tw *Tween) Reset() {
+	tw.Elapsed = 0
+}
+
+// Progress returns the eased progress through the animation, where 0 is the start and 1 is
+// the end.
+func (tw *Tween) Progress() float64 {
+	if tw.Duration <= 0 {
+		return 1
+	}
+	easing := tw.Easing
+	if easing == nil {
+		easing = Linear
+	}
+	return easing(clamp(float64(tw.Elapsed) / float64(tw.Duration)))
+}
+
+// CompositionState holds the properties of a raster.Composition which can be animated.
+type CompositionState struct {
+	Position       image.Point
+	Transformation affine.Transformation
+	Opacity        float64
+}
+
+// StateOf returns the current state of the composition.
+func StateOf(c *raster.Composition) CompositionState {
+	return CompositionState{
+		Position:       c.Position,
+		Transformation: c.Transformation,
+		Opacity:        1 - c.Transparency,
+	}
+}
+
+// CompositionTween animates a raster.Composition from one state to another.
+type CompositionTween struct {
+	*Tween
+	Composition *raster.Composition
+	From        CompositionState
+	To          CompositionState
+}
+
+// NewCompositionTween creates an animation which moves the composition from its current
+// state to the target state.
+func NewCompositionTween(c *raster.Composition, to CompositionState, duration time.Duration, easing Easing) *CompositionTween {
+	return &CompositionTween{
+		Tween:       New(duration, easing),
+		Composition: c,
+		From:        StateOf(c),
+		To:          to,
+	}
+}
+
+// Tick advances the animation by d and updates the composition, returning true when the
+// animation has finished.
+func (ct *CompositionTween) Tick(d time.Duration) (done bool) {
+	done = ct.Advance(d)
+	t := ct.Progress()
+	ct.Composition.Position = Point(ct.From.Position, ct.To.Position, t)
+	ct.Composition.Transformation = Transformation(ct.From.Transformation, ct.To.Transformation, t)
+	ct.Composition.Transparency = 1 - clamp(Float(ct.From.Opacity, ct.To.Opacity, t))
+	return done
+}
@@ -0,0 +1,166 @@
+package tween
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/a-h/raster"
+	"github.com/a-h/raster/affine"
+
+	"golang.org/x/image/colornames"
+)
+
+func TestEasingsStartAndEndInTheRightPlace(t *testing.T) {
+	easings := map[string]Easing{
+		"Linear":           Linear,
+		"EaseInQuad":       EaseInQuad,
+		"EaseOutQuad":      EaseOutQuad,
+		"EaseInOutQuad":    EaseInOutQuad,
+		"EaseInCubic":      EaseInCubic,
+		"EaseOutCubic":     EaseOutCubic,
+		"EaseInOutCubic":   EaseInOutCubic,
+		"EaseInElastic":    EaseInElastic,
+		"EaseOutElastic":   EaseOutElastic,
+		"EaseInOutElastic": EaseInOutElastic,
+		"EaseInBounce":     EaseInBounce,
+		"EaseOutBounce":    EaseOutBounce,
+		"EaseInOutBounce":  EaseInOutBounce,
+	}
+
+	for name, easing := range easings {
+		if actual := easing(0); !isWithin(actual, 0) {
+			t.Errorf("%s: expected 0 at the start, got %v", name, actual)
+		}
+		if actual := easing(1); !isWithin(actual, 1) {
+			t.Errorf("%s: expected 1 at the end, got %v", name, actual)
+		}
+	}
+}
+
+func TestEasings(t *testing.T) {
+	tests := []struct {
+		name     string
+		easing   Easing
+		input    float64
+		expected float64
+	}{
+		{name: "linear", easing: Linear, input: 0.25, expected: 0.25},
+		{name: "in quad", easing: EaseInQuad, input: 0.5, expected: 0.25},
+		{name: "out quad", easing: EaseOutQuad, input: 0.5, expected: 0.75},
+		{name: "in out quad", easing: EaseInOutQuad, input: 0.25, expected: 0.125},
+		{name: "in out quad half way", easing: EaseInOutQuad, input: 0.5, expected: 0.5},
+		{name: "in cubic", easing: EaseInCubic, input: 0.5, expected: 0.125},
+		{name: "out cubic", easing: EaseOutCubic, input: 0.5, expected: 0.875},
+		{name: "out bounce", easing: EaseOutBounce, input: 0.5, expected: 0.765625},
+	}
+
+	for _, test := range tests {
+		if actual := test.easing(test.input); !isWithin(actual, test.expected) {
+			t.Errorf("%s: for input %v, expected %v, got %v", test.name, test.input, test.expected, actual)
+		}
+	}
+}
+
+func TestThatElasticOvershoots(t *testing.T) {
+	overshot := false
+	for i := 0; i <= 100; i++ {
+		if EaseOutElastic(float64(i)/100) > 1 {
+			overshot = true
+		}
+	}
+	if !overshot {
+		t.Error("expected EaseOutElastic to overshoot the end")
+	}
+}
+
+func TestInterpolation(t *testing.T) {
+	if actual := Float(10, 20, 0.5); actual != 15 {
+		t.Errorf("Float: expected 15, got %v", actual)
+	}
+	if actual := Int(0, 3, 0.5); actual != 2 {
+		t.Errorf("Int: expected 1.5 to round to even (2), got %v", actual)
+	}
+	if actual, expected := Point(image.Point{0, 100}, image.Point{100, 0}, 0.25), (image.Point{25, 75}); actual != expected {
+		t.Errorf("Point: expected %v, got %v", expected, actual)
+	}
+	if actual, expected := Color(colornames.Black, colornames.White, 0.5), (color.RGBA{0x80, 0x80, 0x80, 0xff}); actual != expected {
+		t.Errorf("Color: expected %v, got %v", expected, actual)
+	}
+	if actual := Angle(350, 10, 0.5); !isWithin(actual, 360) {
+		t.Errorf("Angle: expected the shortest route from 350 to 10 to pass through 360, got %v", actual)
+	}
+	if actual := Angle(10, 350, 0.5); !isWithin(actual, 0) {
+		t.Errorf("Angle: expected the shortest route from 10 to 350 to pass through 0, got %v", actual)
+	}
+}
+
+func TestTransformationInterpolation(t *testing.T) {
+	from := affine.NewTransformation(affine.IdentityMatrix)
+	to := affine.NewRotationTransformation(90)
+
+	halfway := Transformation(from, to, 0.5).Decompose()
+	if !isWithin(halfway.Rotation, 45) {
+		t.Errorf("expected half way through the rotation to be 45 degrees, got %v", halfway.Rotation)
+	}
+	// Interpolating the matrix directly would shrink the shape half way through.
+	if !isWithin(halfway.ScaleX, 1) || !isWithin(halfway.ScaleY, 1) {
+		t.Errorf("expected the scale to remain at 1, got %v, %v", halfway.ScaleX, halfway.ScaleY)
+	}
+}
+
+func TestTween(t *testing.T) {
+	tw := New(time.Second, nil)
+	if tw.Progress() != 0 {
+		t.Errorf("expected no progress at the start, got %v", tw.Progress())
+	}
+	if tw.Advance(time.Millisecond * 250) {
+		t.Error("expected the tween not to be finished after 250ms")
+	}
+	if tw.Progress() != 0.25 {
+		t.Errorf("expected progress of 0.25, got %v", tw.Progress())
+	}
+	if !tw.Advance(time.Second) {
+		t.Error("expected the tween to be finished")
+	}
+	if tw.Progress() != 1 {
+		t.Errorf("expected the progress to stop at 1, got %v", tw.Progress())
+	}
+	tw.Reset()
+	if tw.Done() {
+		t.Error("expected the tween to be restarted after a reset")
+	}
+}
+
+func TestCompositionTween(t *testing.T) {
+	c := raster.NewComposition(image.Point{0, 0}, raster.NewSquare(image.Point{0, 0}, 10, colornames.Red))
+	ct := NewCompositionTween(c, CompositionState{
+		Position:       image.Point{100, 50},
+		Transformation: affine.NewRotationTransformation(90),
+		Opacity:        0,
+	}, time.Second, Linear)
+
+	ct.Tick(time.Millisecond * 500)
+	if expected := (image.Point{50, 25}); c.Position != expected {
+		t.Errorf("expected position %v, got %v", expected, c.Position)
+	}
+	if !isWithin(c.Transparency, 0.5) {
+		t.Errorf("expected transparency 0.5, got %v", c.Transparency)
+	}
+	if rotation := c.Transformation.Decompose().Rotation; !isWithin(rotation, 45) {
+		t.Errorf("expected rotation of 45, got %v", rotation)
+	}
+
+	if !ct.Tick(time.Millisecond * 500) {
+		t.Error("expected the animation to be finished")
+	}
+	if expected := (image.Point{100, 50}); c.Position != expected {
+		t.Errorf("expected position %v, got %v", expected, c.Position)
+	}
+}
+
+func isWithin(actual, expected float64) bool {
+	return math.Abs(actual-expected) < 0.0001
+}
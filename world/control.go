@@ -0,0 +1,85 @@
+package world
+
+import (
+	"context"
+	"sync"
+)
+
+// controls pause, resume and step the World while it runs. The zero value is running.
+type controls struct {
+	m      sync.Mutex
+	paused bool
+	steps  int
+	// changed is closed, and replaced, when the state changes, to wake up a paused World.
+	changed chan struct{}
+}
+
+// Pause stops the World after the current frame, until Resume or Step is called.
+func (w *World) Pause() {
+	w.controls.m.Lock()
+	defer w.controls.m.Unlock()
+	w.controls.paused = true
+	w.controls.notify()
+}
+
+// Resume restarts a paused World.
+func (w *World) Resume() {
+	w.controls.m.Lock()
+	defer w.controls.m.Unlock()
+	w.controls.paused = false
+	w.controls.steps = 0
+	w.controls.notify()
+}
+
+// Step pauses the World, and lets it run a single frame.
+func (w *World) Step() {
+	w.controls.m.Lock()
+	defer w.controls.m.Unlock()
+	w.controls.steps++
+	w.controls.paused = true
+	w.controls.notify()
+}
+
+// Paused returns true if the World is paused.
+func (w *World) Paused() bool {
+	w.controls.m.Lock()
+	defer w.controls.m.Unlock()
+	return w.controls.paused
+}
+
+// notify wakes up anything waiting for the state to change. The lock must be held.
+func (c *controls) notify() {
+	if c.changed != nil {
+		close(c.changed)
+	}
+	c.changed = make(chan struct{})
+}
+
+// wait blocks until the World is allowed to run a frame, returning whether the frame is a
+// single step, and whether it had to wait.
+func (c *controls) wait(ctx context.Context) (step, waited bool, err error) {
+	for {
+		c.m.Lock()
+		if !c.paused {
+			c.m.Unlock()
+			return false, waited, nil
+		}
+		if c.steps > 0 {
+			c.steps--
+			c.m.Unlock()
+			return true, waited, nil
+		}
+		if c.changed == nil {
+			c.changed = make(chan struct{})
+		}
+		changed := c.changed
+		c.m.Unlock()
+
+		waited = true
+		select {
+		case <-ctx.Done():
+			return false, waited, ctx.Err()
+		case <-changed:
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package world
+
+import (
+	"image"
+	"time"
+
+	"github.com/a-h/raster/actor"
+	"github.com/a-h/raster/affine"
+	"github.com/a-h/raster/tween"
+)
+
+// pose is where an actor is, and which way it's facing, after a physics step.
+type pose struct {
+	Position image.Point
+	Angle    float64
+}
+
+// poseOf returns the current pose of the actor.
+func poseOf(a actor.Actor) pose {
+	return pose{
+		Position: a.Composition().Position,
+		Angle:    a.State().Angle,
+	}
+}
+
+// posesOf returns the current poses of the actors, reusing the poses slice if possible.
+func posesOf(actors []actor.Actor, poses []pose) []pose {
+	poses = poses[:0]
+	for _, a := range actors {
+		poses = append(poses, poseOf(a))
+	}
+	return poses
+}
+
+// apply moves the actor to the pose.
+func (p pose) apply(a actor.Actor) {
+	center := image.Point{a.Composition().Bounds().Dx() / 2, a.Composition().Bounds().Dy() / 2}
+	a.Composition().Transformation = affine.NewRotationAboutTransformation(p.Angle, center)
+	a.Composition().Position = p.Position
+}
+
+// interpolate returns the pose the fraction alpha (0 to 1) of the way from the previous pose
+// to the current one. Angles turn the shortest way round.
+func interpolate(previous, current pose, alpha float64) pose {
+	return pose{
+		Position: tween.Point(previous.Position, current.Position, alpha),
+		Angle:    tween.Angle(previous.Angle, current.Angle, alpha),
+	}
+}
+
+// steps returns the number of whole timesteps in the accumulated time, and the time left
+// over. At most max steps are returned, and any extra time is dropped, so that a world
+// which can't keep up doesn't spend all of its time on physics.
+func steps(accumulated, timestep time.Duration, max int) (n int, remainder time.Duration) {
+	n = int(accumulated / timestep)
+	remainder = accumulated % timestep
+	if n > max {
+		n = max
+	}
+	return n, remainder
+}
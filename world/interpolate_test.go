@@ -0,0 +1,100 @@
+package world
+
+import (
+	"image"
+	"testing"
+	"time"
+)
+
+func TestInterpolate(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous pose
+		current  pose
+		alpha    float64
+		expected pose
+	}{
+		{
+			name:     "start",
+			previous: pose{Position: image.Point{0, 0}, Angle: 0},
+			current:  pose{Position: image.Point{10, 20}, Angle: 90},
+			alpha:    0,
+			expected: pose{Position: image.Point{0, 0}, Angle: 0},
+		},
+		{
+			name:     "half way",
+			previous: pose{Position: image.Point{0, 0}, Angle: 0},
+			current:  pose{Position: image.Point{10, 20}, Angle: 90},
+			alpha:    0.5,
+			expected: pose{Position: image.Point{5, 10}, Angle: 45},
+		},
+		{
+			name:     "end",
+			previous: pose{Position: image.Point{0, 0}, Angle: 0},
+			current:  pose{Position: image.Point{10, 20}, Angle: 90},
+			alpha:    1,
+			expected: pose{Position: image.Point{10, 20}, Angle: 90},
+		},
+		{
+			name:     "moving backwards",
+			previous: pose{Position: image.Point{10, 10}},
+			current:  pose{Position: image.Point{0, 7}},
+			alpha:    0.2,
+			expected: pose{Position: image.Point{8, 9}},
+		},
+		{
+			name:     "turning clockwise past 360",
+			previous: pose{Angle: 350},
+			current:  pose{Angle: 10},
+			alpha:    0.5,
+			expected: pose{Angle: 360},
+		},
+		{
+			name:     "turning anticlockwise past 0",
+			previous: pose{Angle: 10},
+			current:  pose{Angle: 350},
+			alpha:    0.5,
+			expected: pose{Angle: 0},
+		},
+	}
+
+	for _, test := range tests {
+		actual := interpolate(test.previous, test.current, test.alpha)
+		if actual != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestSteps(t *testing.T) {
+	tests := []struct {
+		accumulated       time.Duration
+		expectedSteps     int
+		expectedRemainder time.Duration
+	}{
+		{
+			accumulated:       time.Millisecond * 5,
+			expectedSteps:     0,
+			expectedRemainder: time.Millisecond * 5,
+		},
+		{
+			accumulated:       time.Millisecond * 25,
+			expectedSteps:     2,
+			expectedRemainder: time.Millisecond * 5,
+		},
+		{
+			// Too far behind, so the extra time is dropped.
+			accumulated:       time.Millisecond * 1005,
+			expectedSteps:     10,
+			expectedRemainder: time.Millisecond * 5,
+		},
+	}
+
+	for _, test := range tests {
+		steps, remainder := steps(test.accumulated, time.Millisecond*10, 10)
+		if steps != test.expectedSteps || remainder != test.expectedRemainder {
+			t.Errorf("%v: expected %d steps and %v remaining, got %d and %v", test.accumulated,
+				test.expectedSteps, test.expectedRemainder, steps, remainder)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package world
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// NullPublisher discards the frames, e.g. to benchmark a world without the cost of
+// displaying it.
+type NullPublisher struct{}
+
+// Publish does nothing.
+func (NullPublisher) Publish(img draw.Image) {}
+
+// MemoryPublisher keeps a copy of each frame in memory, e.g. to check what a world has
+// drawn in tests. It's safe to read the frames while the world is running.
+type MemoryPublisher struct {
+	// Max is the maximum number of frames to keep. When it's reached, the oldest frames are
+	// discarded. Zero keeps every frame.
+	Max int
+
+	m         sync.Mutex
+	published *sync.Cond
+	frames    []*image.RGBA
+	count     int
+}
+
+// NewMemoryPublisher creates a publisher which keeps up to max frames, or every frame if max
+// is zero.
+func NewMemoryPublisher(max int) *MemoryPublisher {
+	return &MemoryPublisher{
+		Max: max,
+	}
+}
+
+// Publish stores a copy of the img.
+func (p *MemoryPublisher) Publish(img draw.Image) {
+	b := img.Bounds()
+	frame := image.NewRGBA(b)
+	draw.Draw(frame, b, img, b.Min, draw.Src)
+
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.frames = append(p.frames, frame)
+	if p.Max > 0 && len(p.frames) > p.Max {
+		p.frames = p.frames[len(p.frames)-p.Max:]
+	}
+	p.count++
+	p.cond().Broadcast()
+}
+
+// cond must be called with the lock held.
+func (p *MemoryPublisher) cond() *sync.Cond {
+	if p.published == nil {
+		p.published = sync.NewCond(&p.m)
+	}
+	return p.published
+}
+
+// Frames returns the frames which have been kept, oldest first.
+func (p *MemoryPublisher) Frames() []*image.RGBA {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return append([]*image.RGBA(nil), p.frames...)
+}
+
+// Count returns the number of frames which have been published, including any which have
+// been discarded.
+func (p *MemoryPublisher) Count() int {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.count
+}
+
+// Wait blocks until at least n frames have been published.
+func (p *MemoryPublisher) Wait(n int) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	for p.count < n {
+		p.cond().Wait()
+	}
+}
+
+// PNGPublisher writes each frame to a numbered PNG file in a directory, e.g. to turn into
+// a video with ffmpeg.
+type PNGPublisher struct {
+	// Dir is the directory the files are written to. It's created if it doesn't exist.
+	Dir string
+	// Prefix is added to the start of each file name.
+	Prefix string
+
+	frame int
+	err   error
+}
+
+// NewPNGPublisher creates a publisher which writes files named frame000000.png,
+// frame000001.png etc. to the dir.
+func NewPNGPublisher(dir string) *PNGPublisher {
+	return &PNGPublisher{
+		Dir:    dir,
+		Prefix: "frame",
+	}
+}
+
+// Publish writes the img to the next file. Errors are reported by Err, see ErrorPublisher.
+// Once an error has occurred, no more frames are written.
+func (p *PNGPublisher) Publish(img draw.Image) {
+	if p.err != nil {
+		return
+	}
+	p.err = p.write(img)
+	p.frame++
+}
+
+func (p *PNGPublisher) write(img draw.Image) error {
+	if err := os.MkdirAll(p.Dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(p.Dir, fmt.Sprintf("%s%06d.png", p.Prefix, p.frame)))
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Err returns the first error which occurred while writing the frames.
+func (p *PNGPublisher) Err() error {
+	return p.err
+}
@@ -0,0 +1,142 @@
+package world
+
+import (
+	"context"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/a-h/raster"
+	"github.com/a-h/raster/actor"
+
+	"golang.org/x/image/colornames"
+)
+
+func newTestWorld(p Publisher) *World {
+	bounds := image.Rect(0, 0, 100, 100)
+	background := image.NewRGBA(bounds)
+	draw.Draw(background, bounds, image.NewUniform(colornames.White), image.ZP, draw.Src)
+	ball := &actor.CompositionActor{
+		C: raster.NewComposition(image.Point{40, 0}, raster.NewCircle(image.Point{10, 10}, 10, colornames.Black)),
+		S: &actor.State{},
+	}
+	return &World{
+		Background: background,
+		Actors:     []actor.Actor{ball},
+		Physics:    Physics{Gravity: 5},
+		Target:     image.NewRGBA(bounds),
+		Publisher:  p,
+		Tick:       time.Millisecond,
+	}
+}
+
+// run runs the world until wait returns, and returns the error from Run.
+func run(w *World, wait func()) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+	wait()
+	cancel()
+	return <-done
+}
+
+func TestMemoryPublisher(t *testing.T) {
+	p := NewMemoryPublisher(3)
+	w := newTestWorld(p)
+	run(w, func() { p.Wait(5) })
+
+	if p.Count() < 5 {
+		t.Errorf("expected at least 5 frames to be published, got %d", p.Count())
+	}
+	frames := p.Frames()
+	if len(frames) != 3 {
+		t.Fatalf("expected the last 3 frames to be kept, got %d", len(frames))
+	}
+	if frames[2].RGBAAt(0, 0) != colornames.White {
+		t.Errorf("expected the background to be drawn, got %v", frames[2].RGBAAt(0, 0))
+	}
+}
+
+func TestThatMemoryPublisherCopiesFrames(t *testing.T) {
+	p := NewMemoryPublisher(0)
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	p.Publish(img)
+	img.SetRGBA(0, 0, colornames.Red)
+	p.Publish(img)
+
+	frames := p.Frames()
+	if len(frames) != 2 {
+		t.Fatalf("expected 2 frames, got %d", len(frames))
+	}
+	if frames[0].RGBAAt(0, 0) == colornames.Red {
+		t.Errorf("expected the first frame not to change when the image does")
+	}
+	if frames[1].RGBAAt(0, 0) != colornames.Red {
+		t.Errorf("expected the second frame to be red, got %v", frames[1].RGBAAt(0, 0))
+	}
+}
+
+func TestNullPublisher(t *testing.T) {
+	w := newTestWorld(NullPublisher{})
+	ctx, cancel := context.WithTimeout(context.Background(), w.Tick*20)
+	defer cancel()
+	if err := w.Run(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected the world to run until the deadline, got %v", err)
+	}
+	// The frames are still drawn, they just aren't published anywhere.
+	if actual := w.Target.At(0, 0); actual != colornames.White {
+		t.Errorf("expected the background to be drawn, got %v", actual)
+	}
+}
+
+type publisherFunc func(img draw.Image)
+
+func (f publisherFunc) Publish(img draw.Image) {
+	f(img)
+}
+
+func TestPNGPublisher(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "frames")
+	p := NewPNGPublisher(dir)
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	img.SetRGBA(5, 5, colornames.Red)
+	p.Publish(img)
+	p.Publish(img)
+
+	if err := p.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"frame000000.png", "frame000001.png"} {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("expected %s to be written: %v", name, err)
+		}
+		decoded, err := png.Decode(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: failed to decode: %v", name, err)
+		}
+		if r, _, _, _ := decoded.At(5, 5).RGBA(); r != 0xffff {
+			t.Errorf("%s: expected the red pixel to be written, got %v", name, decoded.At(5, 5))
+		}
+	}
+}
+
+func TestPNGPublisherKeepsTheFirstError(t *testing.T) {
+	// A file can't be used as a directory.
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := NewPNGPublisher(file)
+	p.Publish(image.NewRGBA(image.Rect(0, 0, 1, 1)))
+	if p.Err() == nil {
+		t.Errorf("expected an error")
+	}
+}
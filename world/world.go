@@ -1,14 +1,18 @@
 package world
 
 import (
+	"context"
+	"fmt"
 	"image"
 	"image/draw"
+	"sync/atomic"
 	"time"
 
 	"github.com/Sirupsen/logrus"
 
 	"github.com/a-h/raster/actor"
-	"github.com/a-h/raster/affine"
+	"github.com/a-h/raster/camera"
+	"github.com/a-h/raster/sparse"
 )
 
 // World represents a world where things happen. It controls gravity, space and time.
@@ -19,20 +23,72 @@ type World struct {
 	Target     draw.Image
 	Publisher  Publisher
 	Tick       time.Duration
+	// Camera is optional. When it's set, the actors move around a world the size of the
+	// Camera's Bounds (or the Target, if the Bounds are empty), and the Camera maps them
+	// onto the Target. The Background stays fixed to the Target.
+	Camera *camera.Camera
+	// Follow is the actor which the Camera follows, if any.
+	Follow actor.Actor
+	// Overrun determines what happens when a frame takes longer than the Tick to draw.
+	Overrun OverrunPolicy
+	// Timestep is optional. When it's set, the actors are moved once for each Timestep of
+	// real time, however long the frames take to draw, and are drawn part way between their
+	// last two positions, so that they move smoothly. Otherwise, they're moved once per frame.
+	Timestep time.Duration
+
+	controls controls
+	overruns uint64
+	// sprite is where each actor is drawn before the Camera maps it onto the Target. It's
+	// cleared and reused for each actor.
+	sprite *sparse.Image
 }
 
 type Publisher interface {
 	Publish(img draw.Image)
 }
 
+// An ErrorPublisher is a Publisher which can fail, such as the PNGPublisher. Publish can't
+// return an error, so an ErrorPublisher keeps the first error which occurs, and returns it
+// from Err. Run checks Err after each frame is published, and stops if it returns an error.
+type ErrorPublisher interface {
+	Publisher
+	Err() error
+}
+
 type Physics struct {
 	Gravity float64
 }
 
-func (w *World) Run(stopper <-chan bool) {
+// OverrunPolicy determines what the World does when a frame takes longer than the Tick.
+type OverrunPolicy int
+
+const (
+	// SlowDown starts the next frame straight away, so the world runs slower than real time
+	// until the frames fit in the Tick again.
+	SlowDown OverrunPolicy = iota
+	// Skip drops the missed ticks, and waits for the start of the next one, so that frames
+	// are always drawn in step with the Tick.
+	Skip
+	// CatchUp moves the actors once for each missed tick before drawing the next frame, so
+	// that the world keeps to real time.
+	//
+	// When the World has a Timestep, the actors always keep to real time, so CatchUp is the
+	// same as SlowDown.
+	CatchUp
+)
+
+// maxCatchUp is the most ticks (or Timesteps) which the actors are moved for in a single frame,
+// so that a world which can never keep up doesn't spend all of its time moving the actors.
+const maxCatchUp = 10
+
+// Run draws a frame every Tick until the ctx is cancelled, returning the ctx's error, or an
+// ErrorPublisher fails, returning its error.
+func (w *World) Run(ctx context.Context) error {
 	logrus.Debugf("drawing background")
 	draw.Draw(w.Target, w.Target.Bounds(), w.Background, image.ZP, draw.Src)
-	w.Publisher.Publish(w.Target)
+	if err := w.publish(); err != nil {
+		return err
+	}
 
 	// Store the locations of each sprite.
 	logrus.Debugf("storing sprite locations")
@@ -41,48 +97,173 @@ func (w *World) Run(stopper <-chan bool) {
 		areas[i] = a.Composition().Bounds()
 	}
 
+	// The poses of the actors before the last time they moved, to interpolate from.
+	previous := posesOf(w.Actors, nil)
+	current := posesOf(w.Actors, nil)
+	var accumulated time.Duration
+
+	next := time.Now()
+	last := next
+	missed := 0
 	for {
-		select {
-		case <-stopper:
+		if err := ctx.Err(); err != nil {
 			logrus.Debug("received stop signal")
-			return
-		default:
-			logrus.Debugf("drawing background")
-
-			start := time.Now()
+			return err
+		}
+		step, waited, err := w.controls.wait(ctx)
+		if err != nil {
+			logrus.Debug("received stop signal while paused")
+			return err
+		}
+		if waited {
+			// Don't try and catch up on the time spent paused.
+			next = time.Now()
+			last = next
+			missed = 0
+		}
 
-			for _, a := range areas {
-				// Draw the background over the current location of the actors.
-				logrus.Debugf("drawing background over %v", a)
-				a = image.Rect(a.Min.X, a.Min.Y, a.Max.X, a.Max.Y)
-				draw.Draw(w.Target, a, w.Background, a.Min, draw.Src)
+		start := time.Now()
+		updates, alpha := missed+1, 1.0
+		if w.Timestep > 0 {
+			accumulated += start.Sub(last)
+			updates, accumulated = steps(accumulated, w.Timestep, maxCatchUp)
+			alpha = float64(accumulated) / float64(w.Timestep)
+			if step {
+				// Stepping through a paused world moves it by exactly one Timestep.
+				updates, accumulated, alpha = 1, 0, 1
 			}
+		}
+		last = start
+
+		for _, a := range areas {
+			// Draw the background over the current location of the actors.
+			logrus.Debugf("drawing background over %v", a)
+			draw.Draw(w.Target, a, w.Background, a.Min, draw.Src)
+		}
 
-			// Update the display and sleep.
-			logrus.Debugf("drawing %d actors", len(w.Actors))
+		logrus.Debugf("moving %d actors %d times", len(w.Actors), updates)
+		for i := 0; i < updates; i++ {
+			previous = posesOf(w.Actors, previous)
+			w.update()
+		}
+		current = posesOf(w.Actors, current)
+
+		if alpha < 1 {
+			logrus.Debugf("moving actors %.2f of the way from their previous positions", alpha)
 			for i, a := range w.Actors {
-				newPosition := a.State().Update(w.Target.Bounds(), a.Composition().Bounds(), a.Composition().Position, w.Physics.Gravity)
-				logrus.Debugf("moving actor from %v to %v", a.Composition().Position, newPosition)
-
-				centerX := a.Composition().Bounds().Dx() / 2
-				centerY := a.Composition().Bounds().Dy() / 2
-				moveToCenter := affine.NewTranslationTransformation(centerX, centerY)
-				rotate := affine.NewRotationTransformation(float64(a.State().Angle))
-				moveBack := affine.NewTranslationTransformation(-centerX, -centerY)
-				a.Composition().Transformation = moveToCenter.Combine(rotate).Combine(moveBack)
-				a.Composition().Position = newPosition
-
-				logrus.Debugf("drawing target")
-				areas[i] = a.Composition().Draw(w.Target)
-				logrus.Debugf("drawn actor at %v", areas[i])
+				interpolate(previous[i], current[i], alpha).apply(a)
+			}
+		}
+		if w.Camera != nil && w.Follow != nil {
+			w.Camera.FollowActor(w.Follow)
+			logrus.Debugf("moved camera to %v", w.Camera.Position)
+		}
+		logrus.Debugf("drawing %d actors", len(w.Actors))
+		for i, a := range w.Actors {
+			areas[i] = w.draw(a)
+			logrus.Debugf("drawn actor at %v", areas[i])
+		}
+		if alpha < 1 {
+			// Put the actors back where the physics left them.
+			for i, a := range w.Actors {
+				current[i].apply(a)
+			}
+		}
+
+		logrus.Debugf("publishing frame")
+		if err := w.publish(); err != nil {
+			return err
+		}
+
+		if w.Tick <= 0 {
+			continue
+		}
+		next = next.Add(w.Tick * time.Duration(missed+1))
+		now := time.Now()
+		logrus.Debugf("rendered frame in %v of budget %v, %v remaining", now.Sub(start), w.Tick, next.Sub(now))
+		missed = 0
+		if behind := now.Sub(next); behind > 0 {
+			atomic.AddUint64(&w.overruns, 1)
+			ticks := int(behind / w.Tick)
+			logrus.Debugf("frame overran by %v", behind)
+			switch w.Overrun {
+			case Skip:
+				next = next.Add(w.Tick * time.Duration(ticks+1))
+			case CatchUp:
+				missed = ticks
+				if missed > maxCatchUp {
+					missed = maxCatchUp
+					next = now.Add(-w.Tick * maxCatchUp)
+				}
+			default:
+				next = now
 			}
+		}
+		if err := sleep(ctx, next.Sub(now)); err != nil {
+			logrus.Debug("received stop signal")
+			return err
+		}
+	}
+}
+
+// Overruns returns the number of frames which have taken longer than the Tick to draw.
+func (w *World) Overruns() uint64 {
+	return atomic.LoadUint64(&w.overruns)
+}
+
+// update moves the actors by a single tick, or Timestep.
+func (w *World) update() {
+	for _, a := range w.Actors {
+		newPosition := a.State().Update(w.bounds(), a.Composition().Bounds(), a.Composition().Position, w.Physics.Gravity)
+		logrus.Debugf("moving actor from %v to %v", a.Composition().Position, newPosition)
+		pose{Position: newPosition, Angle: a.State().Angle}.apply(a)
+	}
+}
 
-			logrus.Debugf("publishing frame")
-			w.Publisher.Publish(w.Target)
-			duration := time.Now().Sub(start)
-			remaining := w.Tick - duration
-			logrus.Debugf("rendered frame in %v of budget %v, %v remaining", duration, w.Tick, remaining)
-			time.Sleep(remaining)
+// publish publishes the target, returning the Publisher's error if it's an ErrorPublisher.
+func (w *World) publish() error {
+	w.Publisher.Publish(w.Target)
+	if p, ok := w.Publisher.(ErrorPublisher); ok {
+		if err := p.Err(); err != nil {
+			return fmt.Errorf("world: failed to publish frame: %w", err)
 		}
 	}
+	return nil
+}
+
+// sleep waits for d, or until the ctx is cancelled.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// bounds returns the area which the actors can move around in.
+func (w *World) bounds() image.Rectangle {
+	if w.Camera != nil && !w.Camera.Bounds.Empty() {
+		return w.Camera.Bounds
+	}
+	return w.Target.Bounds()
+}
+
+// draw draws the actor onto the target, through the camera if there is one, returning the
+// area drawn on the target.
+func (w *World) draw(a actor.Actor) image.Rectangle {
+	if w.Camera == nil {
+		return a.Composition().Draw(w.Target)
+	}
+	if w.sprite == nil || w.sprite.Bounds() != w.bounds() {
+		w.sprite = sparse.NewImage(w.bounds())
+	}
+	defer w.sprite.Clear()
+	a.Composition().Draw(w.sprite)
+	return w.Camera.Draw(w.Target, w.sprite)
 }
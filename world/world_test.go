@@ -0,0 +1,264 @@
+package world
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/draw"
+	"testing"
+	"time"
+
+	"github.com/a-h/raster"
+	"github.com/a-h/raster/actor"
+	"github.com/a-h/raster/camera"
+
+	"golang.org/x/image/colornames"
+)
+
+// newCountingWorld creates a world where the velocity of the actor is the number of times
+// it's been moved.
+func newCountingWorld(p Publisher) (*World, *actor.State) {
+	w := newTestWorld(p)
+	state := &actor.State{Acceleration: 1}
+	w.Actors = []actor.Actor{&actor.CompositionActor{
+		C: raster.NewComposition(image.Point{}, raster.NewCircle(image.Point{5, 5}, 5, colornames.Black)),
+		S: state,
+	}}
+	w.Physics.Gravity = 0
+	return w, state
+}
+
+func TestThatRunReturnsTheContextError(t *testing.T) {
+	p := NewMemoryPublisher(1)
+	w := newTestWorld(p)
+	if err := run(w, func() { p.Wait(2) }); err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+type failingPublisher struct {
+	frames, failAfter int
+}
+
+var errPublish = errors.New("publish failed")
+
+func (p *failingPublisher) Publish(img draw.Image) {
+	p.frames++
+}
+
+func (p *failingPublisher) Err() error {
+	if p.frames > p.failAfter {
+		return errPublish
+	}
+	return nil
+}
+
+func TestThatRunStopsWhenThePublisherFails(t *testing.T) {
+	p := &failingPublisher{failAfter: 3}
+	w := newTestWorld(p)
+	err := w.Run(context.Background())
+	if !errors.Is(err, errPublish) {
+		t.Errorf("expected the publish error, got %v", err)
+	}
+	if p.frames != 4 {
+		t.Errorf("expected 4 frames to be published, got %d", p.frames)
+	}
+}
+
+func TestOverrun(t *testing.T) {
+	tick := time.Millisecond * 50
+	tests := []struct {
+		policy           OverrunPolicy
+		expectedVelocity float64
+		expectedWait     time.Duration
+	}{
+		{
+			policy:           SlowDown,
+			expectedVelocity: 2,
+		},
+		{
+			// Wait for the start of the 4th tick.
+			policy:           Skip,
+			expectedVelocity: 2,
+			expectedWait:     tick * 2 / 5,
+		},
+		{
+			// Move the actor for the 2 whole ticks that were missed.
+			policy:           CatchUp,
+			expectedVelocity: 4,
+		},
+	}
+
+	for _, test := range tests {
+		var w *World
+		var state *actor.State
+		var velocities []float64
+		var published []time.Time
+		ctx, cancel := context.WithCancel(context.Background())
+		w, state = newCountingWorld(publisherFunc(func(img draw.Image) {
+			velocities = append(velocities, state.Velocity)
+			if len(velocities) == 2 {
+				// Overrun the first frame by 2.5 ticks.
+				time.Sleep(tick*7/2 - time.Since(published[0]))
+			}
+			published = append(published, time.Now())
+			if len(velocities) == 3 {
+				cancel()
+			}
+		}))
+		w.Tick = tick
+		w.Overrun = test.policy
+		w.Run(ctx)
+
+		if len(velocities) != 3 {
+			t.Fatalf("policy %d: expected 3 frames, got %d", test.policy, len(velocities))
+		}
+		if velocities[2] != test.expectedVelocity {
+			t.Errorf("policy %d: expected velocity %v, got %v", test.policy, test.expectedVelocity, velocities[2])
+		}
+		if w.Overruns() != 1 {
+			t.Errorf("policy %d: expected 1 overrun, got %d", test.policy, w.Overruns())
+		}
+		if wait := published[2].Sub(published[1]); wait < test.expectedWait {
+			t.Errorf("policy %d: expected to wait at least %v before the next frame, waited %v", test.policy, test.expectedWait, wait)
+		}
+	}
+}
+
+func TestPauseResumeAndStep(t *testing.T) {
+	p := NewMemoryPublisher(1)
+	w, state := newCountingWorld(p)
+	w.Pause()
+	if !w.Paused() {
+		t.Errorf("expected the world to be paused")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+
+	// The background is published before the world is paused.
+	p.Wait(1)
+	w.Step()
+	w.Step()
+	p.Wait(3)
+	time.Sleep(w.Tick * 10)
+	if p.Count() != 3 {
+		t.Errorf("expected 3 frames while paused, got %d", p.Count())
+	}
+	if !w.Paused() {
+		t.Errorf("expected the world to still be paused after stepping")
+	}
+
+	w.Resume()
+	p.Wait(6)
+	if w.Paused() {
+		t.Errorf("expected the world to be running")
+	}
+
+	// A paused world must still stop.
+	w.Pause()
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	if state.Velocity < 5 {
+		t.Errorf("expected the actor to have moved at least 5 times, got %v", state.Velocity)
+	}
+}
+
+func TestSleep(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := sleep(ctx, -time.Second); err != nil {
+		t.Errorf("expected a negative duration not to wait, got %v", err)
+	}
+	cancel()
+	start := time.Now()
+	if err := sleep(ctx, time.Hour); err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("expected a cancelled sleep to return straight away")
+	}
+}
+
+func TestFixedTimestep(t *testing.T) {
+	var state *actor.State
+	var w *World
+	var positions []int
+	var velocities []float64
+	var start time.Time
+	var elapsed time.Duration
+	ctx, cancel := context.WithCancel(context.Background())
+	w, state = newCountingWorld(publisherFunc(func(img draw.Image) {
+		if start.IsZero() {
+			start = time.Now()
+		}
+		positions = append(positions, w.Actors[0].Composition().Position.X)
+		velocities = append(velocities, state.Velocity)
+		if len(positions) == 6 {
+			elapsed = time.Since(start)
+			cancel()
+		}
+	}))
+	w.Tick = time.Millisecond * 50
+	w.Timestep = time.Millisecond * 10
+	w.Run(ctx)
+
+	// The actor is moved once per Timestep, not once per frame.
+	moves := velocities[len(velocities)-1]
+	if max := float64(elapsed / w.Timestep); moves > max || moves < max/2 {
+		t.Errorf("expected the actor to move up to %v times in %v, moved %v times", max, elapsed, moves)
+	}
+	// The actor is drawn between the positions the physics calculates, but then put back.
+	physics := int(moves * (moves + 1) / 2)
+	for i := 1; i < len(positions); i++ {
+		if positions[i] < positions[i-1] || positions[i] > physics {
+			t.Errorf("frame %d: expected the actor to be drawn between %d and %d, got %d", i, positions[i-1], physics, positions[i])
+		}
+	}
+	if actual := w.Actors[0].Composition().Position.X; actual != physics {
+		t.Errorf("expected the actor to be left at %d, got %d", physics, actual)
+	}
+}
+
+func TestThatSteppingMovesByOneTimestep(t *testing.T) {
+	p := NewMemoryPublisher(1)
+	w, state := newCountingWorld(p)
+	w.Timestep = time.Millisecond
+	w.Pause()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+	p.Wait(1)
+	// Give the time a chance to build up, it should be ignored.
+	time.Sleep(time.Millisecond * 20)
+	w.Step()
+	p.Wait(2)
+	cancel()
+	<-done
+	if state.Velocity != 1 {
+		t.Errorf("expected the actor to move once, moved %v times", state.Velocity)
+	}
+}
+
+func TestThatEachActorIsDrawnThroughTheCameraOnItsOwn(t *testing.T) {
+	w := newTestWorld(NullPublisher{})
+	w.Camera = camera.New(w.Target.Bounds())
+	left := raster.NewComposition(image.Point{0, 0}, raster.NewFilledRectangle(image.Point{}, 10, 10, colornames.Red, colornames.Red))
+	right := raster.NewComposition(image.Point{50, 0}, raster.NewFilledRectangle(image.Point{}, 10, 10, colornames.Blue, colornames.Blue))
+
+	drawnAlone := w.draw(&actor.CompositionActor{C: right, S: &actor.State{}})
+	w.draw(&actor.CompositionActor{C: left, S: &actor.State{}})
+	drawn := w.draw(&actor.CompositionActor{C: right, S: &actor.State{}})
+	if drawn != drawnAlone || drawn.Min.X < 50 {
+		t.Errorf("expected only the second actor to be drawn at %v, got %v", drawnAlone, drawn)
+	}
+	if w.sprite.Len() != 0 {
+		t.Errorf("expected the sprite to be cleared after drawing, got %d pixels", w.sprite.Len())
+	}
+}